@@ -2,8 +2,10 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -13,17 +15,47 @@ import (
 	"smart_elf_standalone/internal/service"
 	"smart_elf_standalone/pkg/config"
 	"smart_elf_standalone/pkg/database"
+	"strings"
 	"syscall"
 	"time"
+
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
+)
+
+// 未配置时的请求停机超时时间与事件后台任务排空超时时间
+const (
+	defaultShutdownTimeout = 15 * time.Second
+	defaultDrainTimeout    = 15 * time.Second
 )
 
 func main() {
-	// 初始化日志
-	initLogger() // 加载配置
-    cfg, err := config.LoadConfig()
+	// 解析命令行参数，配置文件路径也可通过 SMART_ELF_CONFIG 指定
+	configPath := flag.String("config", envOr("SMART_ELF_CONFIG", config.DefaultConfigPath), "配置文件路径")
+	flag.Parse()
+
+	// 子命令
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
+		case "validate-config":
+			os.Exit(runValidateConfig(*configPath, args[1:]))
+		case "migrate":
+			os.Exit(runMigrate(*configPath, args[1:]))
+		default:
+			fmt.Fprintf(os.Stderr, "未知子命令: %s\n可用子命令: validate-config, migrate\n", args[0])
+			os.Exit(2)
+		}
+	}
+
+	// 初始化日志，标准库 log 的输出转交 zerolog，按 logger.level 过滤
+	log.SetFlags(log.Lshortfile)
+	log.SetOutput(stdLogWriter{})
+	// 加载配置
+    cfg, err := config.LoadConfig(*configPath)
     if err != nil {
         log.Fatalf("错误: 加载配置失败: %v\n", err)
     }
+	initLogger(cfg.Logger)
     // 设置全局配置单例，便于其他模块直接使用
     config.SetGlobalConfig(cfg)
 
@@ -39,29 +71,62 @@ func main() {
 	}()
 
 	// 数据库迁移
-	if err := database.AutoMigrate(db); err != nil {
+	applied, err := database.MigrateUp(db)
+	if err != nil {
 		log.Fatalf("错误: 数据库迁移失败: %v\n", err)
 	}
+	log.Printf("信息: 数据库迁移完成: applied=%d\n", applied)
 	// 初始化飞书认证
-	feishuAuth := auth.NewFeishuAuth(cfg.Feishu.ProjectAPIHost, cfg.Feishu.PluginID, cfg.Feishu.PluginSecret)
+	feishuAuth, err := auth.NewFeishuAuth(cfg.Feishu)
+	if err != nil {
+		log.Fatalf("错误: 初始化飞书认证失败: %v\n", err)
+	}
 
 	// 初始化服务
-	configService := service.NewConfigService(db)
-	eventService := service.NewEventService(db, configService, cfg.Feishu)
+	clientRegistry := service.NewClientRegistry(cfg.Feishu)
+	configService := service.NewConfigService(db, clientRegistry)
+	rateLimitService := service.NewRateLimitService(db, cfg.RateLimit)
+	duplicateService := service.NewDuplicateService(db, cfg.Duplicate)
+	ticketService := service.NewTicketService(db)
+	statsService := service.NewStatsService(db)
+	stepService := service.NewTicketStepService(db, cfg.Step)
+	userTokenService := service.NewUserTokenService(db, feishuAuth)
+	metadataService := service.NewMetadataService(configService, clientRegistry, userTokenService, cfg.Metadata)
+	eventService := service.NewEventService(db, configService, rateLimitService, duplicateService, ticketService, stepService, clientRegistry, metadataService, userTokenService, cfg.Group)
+
+	// 启动工单群定时对账与后续步骤定时重试，服务关闭时停止
+	eventService.StartGroupReconciler()
+	eventService.StartStepRetrier()
 
 	// 初始化SmartElf核心组件
-	smartElf := internal.NewSmartElf(configService, eventService)
+	smartElf := internal.NewSmartElf(configService, eventService, ticketService, statsService, stepService, metadataService, userTokenService)
 
 	// 初始化处理器
 	h := handler.NewHandler(smartElf)
 
 	// 设置路由
-	router := handler.SetupRouter(h, feishuAuth, cfg.Feishu.ProjectWebHost)
+	router := handler.SetupRouter(h, feishuAuth, cfg.Feishu.ProjectWebHost, cfg.Proxy)
+
+	// 配置热更新：收到 SIGHUP 时重新加载飞书配置与日志级别，缓存的客户端与令牌按新配置重建
+	config.OnReload(func(cfg *config.Config) {
+		if clientRegistry.UpdateConfig(cfg.Feishu) {
+			metadataService.InvalidateAll()
+		}
+		if err := feishuAuth.UpdateConfig(cfg.Feishu); err != nil {
+			log.Printf("错误: 更新插件令牌配置失败: %v\n", err)
+		}
+		setLogLevel(cfg.Logger.Level)
+	})
+	go watchReload(*configPath)
 
 	// 创建HTTP服务器
+	// 请求的 context 派生自 baseCtx，停机超时后统一取消仍在处理的请求
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
 	srv := &http.Server{
-		Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
-		Handler: router,
+		Addr:        fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
 	}
 
 	// 启动服务器
@@ -78,24 +143,181 @@ func main() {
 	<-quit
 	log.Println("信息: 正在关闭服务器...")
 
-	// 设置5秒的超时时间
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// 先停止接收新请求，再排空事件后台任务，两者分别计时
+	shutdownTimeout := time.Duration(cfg.Server.ShutdownTimeoutSeconds) * time.Second
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
+	drainTimeout := time.Duration(cfg.Server.DrainTimeoutSeconds) * time.Second
+	if drainTimeout <= 0 {
+		drainTimeout = defaultDrainTimeout
+	}
 
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Printf("错误: 服务器关闭失败: %v\n", err)
+		cancelBase()
 	} else {
 		log.Println("信息: 服务器已关闭")
 	}
+	// 事件后台任务在请求处理完成后才全部派生，排空单独计时；Shutdown 会等待被取消的任务释放已领取的步骤后再返回
+	drainCtx, cancelDrain := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancelDrain()
+	if err := eventService.Shutdown(drainCtx); err != nil {
+		log.Printf("错误: 事件后台任务排空失败: %v\n", err)
+	}
+}
+
+// initLogger 按配置设置日志级别与输出格式，LOG_LEVEL 环境变量已在加载配置时并入 logger.level
+func initLogger(cfg config.LoggerConfig) {
+	level := setLogLevel(cfg.Level)
+	if cfg.Format == "console" {
+		zlog.Logger = zlog.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.DateTime})
+	}
+	log.Printf("信息: 日志级别: %s, 格式: %s\n", level, cfg.Format)
+}
+
+// stdLogWriter 将标准库 log 的输出转交 zerolog，使 logger.level 与输出格式同样作用于服务日志。
+// 级别按消息前缀确定：错误、警告、信息分别对应 error、warn、info；无前缀时含 failed 的按 error，其余按 info
+type stdLogWriter struct{}
+
+func (stdLogWriter) Write(p []byte) (int, error) {
+	caller, msg, ok := strings.Cut(strings.TrimRight(string(p), "\n"), ": ")
+	if !ok {
+		caller, msg = "", caller
+	}
+	level := zerolog.InfoLevel
+	switch {
+	case strings.HasPrefix(msg, "错误"):
+		level = zerolog.ErrorLevel
+	case strings.HasPrefix(msg, "警告"):
+		level = zerolog.WarnLevel
+	case strings.HasPrefix(msg, "信息"):
+		level = zerolog.InfoLevel
+	case strings.Contains(msg, "failed"):
+		level = zerolog.ErrorLevel
+	}
+	event := zlog.WithLevel(level)
+	if caller != "" {
+		event = event.Str("caller", caller)
+	}
+	event.Msg(msg)
+	return len(p), nil
+}
+
+// setLogLevel 设置 zerolog 全局日志级别，无效时使用 info
+func setLogLevel(s string) zerolog.Level {
+	level := zerolog.InfoLevel
+	if s != "" {
+		parsed, err := zerolog.ParseLevel(strings.ToLower(s))
+		if err != nil {
+			log.Printf("警告: 无效的日志级别 %q，使用 info: %v\n", s, err)
+		} else {
+			level = parsed
+		}
+	}
+	zerolog.SetGlobalLevel(level)
+	return level
+}
+
+// watchReload 收到 SIGHUP 时重新加载配置，失败时保留当前配置
+func watchReload(configPath string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		log.Println("信息: 收到 SIGHUP，重新加载配置")
+		if _, err := config.Reload(configPath); err != nil {
+			log.Printf("错误: 重新加载配置失败，保留当前配置: %v\n", err)
+		}
+	}
 }
 
-// initLogger 初始化日志
-func initLogger() {
-	// 简化的日志初始化
-	log.SetFlags(log.LstdFlags | log.Lshortfile)
+// runValidateConfig 校验配置文件（含环境变量覆盖）后退出，不启动服务
+func runValidateConfig(configPath string, args []string) int {
+	fs := flag.NewFlagSet("validate-config", flag.ExitOnError)
+	path := fs.String("config", configPath, "配置文件路径")
+	_ = fs.Parse(args)
+
+	if _, err := config.LoadConfig(*path); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	fmt.Printf("配置校验通过: %s\n", *path)
+	return 0
+}
+
+// runMigrate 执行数据库迁移子命令：up 应用全部未应用的迁移，down 回滚最近的迁移，status 查看迁移状态
+func runMigrate(configPath string, args []string) int {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	path := fs.String("config", configPath, "配置文件路径")
+	steps := fs.Int("steps", 1, "down 回滚的迁移数量")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "用法: migrate [--config path] <up|down|status> [--steps n]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+	action := fs.Arg(0)
+	// 允许参数写在子命令之后，如 migrate down --steps 2
+	if fs.NArg() > 1 {
+		_ = fs.Parse(fs.Args()[1:])
+	}
+	if action != "up" && action != "down" && action != "status" {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := config.LoadConfig(*path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	db, err := database.InitDB(&cfg.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "连接数据库失败: %v\n", err)
+		return 1
+	}
+	defer database.CloseDB(db)
+
+	switch action {
+	case "up":
+		n, err := database.MigrateUp(db)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Printf("已应用 %d 个迁移\n", n)
+	case "down":
+		n, err := database.MigrateDown(db, *steps)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		fmt.Printf("已回滚 %d 个迁移\n", n)
+	case "status":
+		states, err := database.MigrationStatus(db)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		for _, s := range states {
+			status := "未应用"
+			if s.Applied {
+				status = "已应用 " + s.AppliedAt.Local().Format(time.DateTime)
+			}
+			if s.Missing {
+				status += "（当前程序中不存在）"
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, status)
+		}
+	}
+	return 0
+}
 
-	// 从环境变量读取日志级别（简化版本）
-	if level := os.Getenv("LOG_LEVEL"); level != "" {
-		log.Printf("信息: 日志级别: %s\n", level)
+// envOr 读取环境变量，未设置时返回默认值
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
 	}
+	return def
 }
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TokenErrorKind 令牌获取失败的类别
+type TokenErrorKind int
+
+const (
+	// TokenErrorNetwork 网络错误或飞书项目服务异常，可稍后重试
+	TokenErrorNetwork TokenErrorKind = iota + 1
+	// TokenErrorAuth 插件凭证被拒绝，需检查 plugin_id、plugin_secret 与令牌类型
+	TokenErrorAuth
+)
+
+// TokenError 获取插件令牌失败
+type TokenError struct {
+	Kind       TokenErrorKind
+	StatusCode int
+	Code       int
+	Msg        string
+	Err        error
+}
+
+func (e *TokenError) Error() string {
+	kind := "network"
+	if e.Kind == TokenErrorAuth {
+		kind = "auth"
+	}
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("failed to get plugin token (%s): %v", kind, e.Err)
+	case e.Code != 0:
+		return fmt.Sprintf("failed to get plugin token (%s): code=%d, msg=%s", kind, e.Code, e.Msg)
+	default:
+		return fmt.Sprintf("failed to get plugin token (%s): status code=%d", kind, e.StatusCode)
+	}
+}
+
+func (e *TokenError) Unwrap() error {
+	return e.Err
+}
+
+// IsAuthError 是否为插件凭证被拒绝导致的失败
+func IsAuthError(err error) bool {
+	var tokenErr *TokenError
+	return errors.As(err, &tokenErr) && tokenErr.Kind == TokenErrorAuth
+}
@@ -4,63 +4,245 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"log"
+	"math/rand"
 	"net/http"
 	"sync"
 	"time"
+
+	"smart_elf_standalone/pkg/config"
+)
+
+// TokenType 插件令牌类型，取值与飞书项目 plugin_token 接口的 type 参数一致
+type TokenType int
+
+const (
+	// TokenTypePlugin 插件令牌
+	TokenTypePlugin TokenType = 0
+	// TokenTypeVirtualPlugin 虚拟插件令牌，插件未发布时用于开发调试
+	TokenTypeVirtualPlugin TokenType = 1
 )
 
+// 令牌刷新的默认配置
+const (
+	defaultTokenRefreshBefore = 5 * time.Minute
+	defaultTokenRefreshJitter = time.Minute
+	tokenRequestTimeout       = 10 * time.Second
+)
+
+// ParseTokenType 解析配置中的令牌类型，为空时返回插件令牌
+func ParseTokenType(s string) (TokenType, error) {
+	switch s {
+	case "", "plugin":
+		return TokenTypePlugin, nil
+	case "virtual_plugin":
+		return TokenTypeVirtualPlugin, nil
+	default:
+		return TokenTypePlugin, fmt.Errorf("unknown plugin token type: %s", s)
+	}
+}
+
+// FeishuAuth 飞书项目插件令牌管理，令牌临近过期时在后台提前刷新，同一时刻只有一个刷新请求
 type FeishuAuth struct {
-	apiHost      string
-	pluginID     string
-	pluginSecret string
-	token        string
-	tokenExpiry  time.Time
-	mu           sync.Mutex
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	settings  authSettings
+	token     string
+	expireAt  time.Time
+	refreshAt time.Time
+	// inflight 进行中的刷新，为 nil 表示当前没有刷新
+	inflight *tokenRefresh
+	// generation 凭证变更次数，用于丢弃按旧凭证获取的令牌
+	generation uint64
+}
+
+// authSettings 获取令牌所需的配置，热更新时整体替换
+type authSettings struct {
+	apiHost       string
+	pluginID      string
+	pluginSecret  string
+	tokenType     TokenType
+	refreshBefore time.Duration
+	refreshJitter time.Duration
+}
+
+// tokenRefresh 一次进行中的令牌刷新，等待者在 done 关闭后读取 err
+type tokenRefresh struct {
+	done chan struct{}
+	err  error
 }
 
-func NewFeishuAuth(apiHost, pluginID, pluginSecret string) *FeishuAuth {
+// NewFeishuAuth 创建插件令牌管理实例
+func NewFeishuAuth(cfg config.FeishuConfig) (*FeishuAuth, error) {
+	settings, err := newAuthSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
 	return &FeishuAuth{
-		apiHost:      apiHost,
-		pluginID:     pluginID,
-		pluginSecret: pluginSecret,
+		settings:   settings,
+		httpClient: &http.Client{Timeout: tokenRequestTimeout},
+	}, nil
+}
+
+// newAuthSettings 由飞书配置生成令牌配置
+func newAuthSettings(cfg config.FeishuConfig) (authSettings, error) {
+	tokenType, err := ParseTokenType(cfg.PluginToken.Type)
+	if err != nil {
+		return authSettings{}, err
+	}
+	refreshBefore := time.Duration(cfg.PluginToken.RefreshBeforeSeconds) * time.Second
+	if refreshBefore <= 0 {
+		refreshBefore = defaultTokenRefreshBefore
 	}
+	refreshJitter := time.Duration(cfg.PluginToken.RefreshJitterSeconds) * time.Second
+	if refreshJitter <= 0 {
+		refreshJitter = defaultTokenRefreshJitter
+	}
+	return authSettings{
+		apiHost:       cfg.ProjectAPIHost,
+		pluginID:      cfg.PluginID,
+		pluginSecret:  cfg.PluginSecret,
+		tokenType:     tokenType,
+		refreshBefore: refreshBefore,
+		refreshJitter: refreshJitter,
+	}, nil
 }
 
-func (a *FeishuAuth) GetToken() (string, error) {
+// UpdateConfig 热更新令牌配置，地址、插件凭证或令牌类型变化时丢弃当前令牌，下次获取时按新配置请求
+func (a *FeishuAuth) UpdateConfig(cfg config.FeishuConfig) error {
+	settings, err := newAuthSettings(cfg)
+	if err != nil {
+		return err
+	}
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	old := a.settings
+	a.settings = settings
+	if old.apiHost != settings.apiHost || old.pluginID != settings.pluginID ||
+		old.pluginSecret != settings.pluginSecret || old.tokenType != settings.tokenType {
+		a.token = ""
+		a.generation++
+		log.Printf("信息: 插件凭证已变更，插件令牌将重新获取")
+	}
+	return nil
+}
+
+// currentSettings 读取当前令牌配置
+func (a *FeishuAuth) currentSettings() authSettings {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.settings
+}
 
-	if a.token == "" || time.Now().After(a.tokenExpiry) {
-		if err := a.refreshToken(); err != nil {
-			return "", err
+// GetToken 获取插件令牌。令牌有效时直接返回，临近过期时触发后台刷新；
+// 令牌不存在或已过期时等待刷新完成
+func (a *FeishuAuth) GetToken() (string, error) {
+	now := time.Now()
+	a.mu.RLock()
+	token, expireAt, refreshAt := a.token, a.expireAt, a.refreshAt
+	a.mu.RUnlock()
+
+	if token != "" && now.Before(expireAt) {
+		if !now.Before(refreshAt) {
+			a.startRefresh()
 		}
+		return token, nil
 	}
 
+	call := a.startRefresh()
+	<-call.done
+	if call.err != nil {
+		return "", call.err
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
 	return a.token, nil
 }
 
+// startRefresh 发起刷新，已有刷新进行中时复用
+func (a *FeishuAuth) startRefresh() *tokenRefresh {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.inflight != nil {
+		return a.inflight
+	}
+	call := &tokenRefresh{done: make(chan struct{})}
+	a.inflight = call
+	go func() {
+		call.err = a.refreshToken()
+		a.mu.Lock()
+		a.inflight = nil
+		a.mu.Unlock()
+		close(call.done)
+	}()
+	return call
+}
+
+// refreshToken 请求新令牌并更新缓存，网络请求期间不持有锁。
+// 请求期间凭证变更时丢弃按旧凭证获取的结果，按新凭证重新请求
 func (a *FeishuAuth) refreshToken() error {
-	url := fmt.Sprintf("%s/open_api/authen/plugin_token", a.apiHost)
+	for {
+		a.mu.RLock()
+		settings, generation := a.settings, a.generation
+		a.mu.RUnlock()
+
+		token, ttl, err := a.fetchToken(settings)
+		now := time.Now()
+
+		a.mu.Lock()
+		if a.generation != generation {
+			a.mu.Unlock()
+			log.Printf("信息: 刷新插件令牌期间凭证已变更，按新凭证重新获取")
+			continue
+		}
+		if err != nil {
+			a.mu.Unlock()
+			log.Printf("refresh plugin token failed,err=%s", err.Error())
+			return err
+		}
+		before := settings.refreshBefore
+		if settings.refreshJitter > 0 {
+			before += time.Duration(rand.Int63n(int64(settings.refreshJitter)))
+		}
+		// 令牌有效期短于提前量时，在有效期过半时刷新
+		if before >= ttl {
+			before = ttl / 2
+		}
+		a.token = token
+		a.expireAt = now.Add(ttl)
+		a.refreshAt = a.expireAt.Add(-before)
+		a.mu.Unlock()
+		return nil
+	}
+}
+
+// fetchToken 调用飞书项目接口获取插件令牌及有效期
+func (a *FeishuAuth) fetchToken(settings authSettings) (string, time.Duration, error) {
+	url := fmt.Sprintf("%s/open_api/authen/plugin_token", settings.apiHost)
 
 	payload := map[string]interface{}{
-		"plugin_id":     a.pluginID,
-		"plugin_secret": a.pluginSecret,
-		"type":          1, //todo
+		"plugin_id":     settings.pluginID,
+		"plugin_secret": settings.pluginSecret,
+		"type":          int(settings.tokenType),
 	}
 
 	jsonPayload, err := json.Marshal(payload)
 	if err != nil {
-		return err
+		return "", 0, err
 	}
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonPayload))
+	resp, err := a.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonPayload))
 	if err != nil {
-		return err
+		return "", 0, &TokenError{Kind: TokenErrorNetwork, Err: err}
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to get token, status code: %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", 0, &TokenError{Kind: TokenErrorAuth, StatusCode: resp.StatusCode}
+	case resp.StatusCode != http.StatusOK:
+		return "", 0, &TokenError{Kind: TokenErrorNetwork, StatusCode: resp.StatusCode}
 	}
 
 	var result struct {
@@ -75,15 +257,12 @@ func (a *FeishuAuth) refreshToken() error {
 	}
 
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return err
+		return "", 0, &TokenError{Kind: TokenErrorNetwork, StatusCode: resp.StatusCode, Err: err}
 	}
 
-	if result.Error.Code != 0 {
-		return fmt.Errorf("failed to get token: %s", result.Error.Msg)
+	if result.Error.Code != 0 || result.Data.Token == "" {
+		return "", 0, &TokenError{Kind: TokenErrorAuth, StatusCode: resp.StatusCode, Code: result.Error.Code, Msg: result.Error.Msg}
 	}
 
-	a.token = result.Data.Token
-	a.tokenExpiry = time.Now().Add(time.Second * time.Duration(result.Data.ExpireTime))
-
-	return nil
+	return result.Data.Token, time.Second * time.Duration(result.Data.ExpireTime), nil
 }
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultSessionTTL 未配置时插件会话的有效期
+const defaultSessionTTL = 2 * time.Hour
+
+// ErrInvalidSession 会话无效或已过期
+var ErrInvalidSession = errors.New("invalid session")
+
+// SessionManager 签发与校验插件会话，会话内容为用户 user_key 与过期时间，使用 HMAC 签名防篡改
+type SessionManager struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewSessionManager 创建会话管理器，secret 为空时随机生成
+func NewSessionManager(secret string, ttl time.Duration) *SessionManager {
+	key := []byte(secret)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			panic(err)
+		}
+		log.Printf("信息: 未配置会话签名密钥，已随机生成，重启后会话失效")
+	}
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	return &SessionManager{secret: key, ttl: ttl}
+}
+
+// Issue 为用户签发会话
+func (m *SessionManager) Issue(userKey string) (string, time.Time) {
+	expireAt := time.Now().Add(m.ttl)
+	payload := userKey + "|" + strconv.FormatInt(expireAt.Unix(), 10)
+	encoded := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	return encoded + "." + m.sign(encoded), expireAt
+}
+
+// Verify 校验会话并返回用户 user_key
+func (m *SessionManager) Verify(session string) (string, error) {
+	encoded, sig, ok := strings.Cut(session, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(m.sign(encoded))) {
+		return "", ErrInvalidSession
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", ErrInvalidSession
+	}
+	idx := strings.LastIndex(string(payload), "|")
+	if idx <= 0 {
+		return "", ErrInvalidSession
+	}
+	expireAt, err := strconv.ParseInt(string(payload[idx+1:]), 10, 64)
+	if err != nil || time.Now().Unix() >= expireAt {
+		return "", ErrInvalidSession
+	}
+	return string(payload[:idx]), nil
+}
+
+// sign 计算签名
+func (m *SessionManager) sign(encoded string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
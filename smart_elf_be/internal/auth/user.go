@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ErrInvalidAuthCode 授权码无效或已过期
+var ErrInvalidAuthCode = errors.New("invalid auth code")
+
+// ErrInvalidRefreshToken 刷新令牌无效或已过期，用户需要重新授权
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// userTokenTypeUser 刷新接口的令牌类型，目前仅支持用户令牌
+const userTokenTypeUser = 1
+
+// UserToken 授权码换取的用户凭证
+type UserToken struct {
+	UserKey         string
+	Token           string
+	ExpireAt        time.Time
+	RefreshToken    string
+	RefreshExpireAt time.Time
+}
+
+// userTokenResult 用户令牌接口的响应
+type userTokenResult struct {
+	Data struct {
+		UserKey                string `json:"user_key"`
+		Token                  string `json:"token"`
+		ExpireTime             int64  `json:"expire_time"`
+		RefreshToken           string `json:"refresh_token"`
+		RefreshTokenExpireTime int64  `json:"refresh_token_expire_time"`
+	} `json:"data"`
+	Error struct {
+		Code int    `json:"code"`
+		Msg  string `json:"msg"`
+	} `json:"error"`
+}
+
+// ExchangeCode 使用前端 JSSDK 获取的授权码换取用户凭证
+func (a *FeishuAuth) ExchangeCode(ctx context.Context, code string) (*UserToken, error) {
+	result, err := a.postUserToken(ctx, "/open_api/authen/user_plugin_token", map[string]interface{}{
+		"code":       code,
+		"grant_type": "authorization_code",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Error.Code != 0 || result.Data.UserKey == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAuthCode, result.Error.Msg)
+	}
+	return result.userToken(result.Data.UserKey), nil
+}
+
+// RefreshUserToken 使用刷新令牌换取新的用户凭证
+func (a *FeishuAuth) RefreshUserToken(ctx context.Context, userKey, refreshToken string) (*UserToken, error) {
+	result, err := a.postUserToken(ctx, "/open_api/authen/refresh_token", map[string]interface{}{
+		"refresh_token": refreshToken,
+		"type":          userTokenTypeUser,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Error.Code != 0 || result.Data.Token == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidRefreshToken, result.Error.Msg)
+	}
+	return result.userToken(userKey), nil
+}
+
+// postUserToken 携带插件令牌调用用户令牌相关接口
+func (a *FeishuAuth) postUserToken(ctx context.Context, apiPath string, payload map[string]interface{}) (*userTokenResult, error) {
+	pluginToken, err := a.GetToken()
+	if err != nil {
+		return nil, err
+	}
+
+	jsonPayload, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.currentSettings().apiHost+apiPath, bytes.NewBuffer(jsonPayload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Plugin-Token", pluginToken)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to request %s, status code: %d", apiPath, resp.StatusCode)
+	}
+
+	var result userTokenResult
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// userToken 转换为用户凭证
+func (r *userTokenResult) userToken(userKey string) *UserToken {
+	now := time.Now()
+	return &UserToken{
+		UserKey:         userKey,
+		Token:           r.Data.Token,
+		ExpireAt:        now.Add(time.Second * time.Duration(r.Data.ExpireTime)),
+		RefreshToken:    r.Data.RefreshToken,
+		RefreshExpireAt: now.Add(time.Second * time.Duration(r.Data.RefreshTokenExpireTime)),
+	}
+}
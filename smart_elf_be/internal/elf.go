@@ -1,8 +1,10 @@
 package internal
 
 import (
+	"context"
 	"errors"
 	"log"
+	"smart_elf_standalone/internal/auth"
 	"smart_elf_standalone/internal/model"
 	"smart_elf_standalone/internal/service"
 
@@ -11,23 +13,38 @@ import (
 
 // SmartElf 智能插件的核心结构体
 type SmartElf struct {
-	ConfigService *service.ConfigService
-	EventService  *service.EventService
+	ConfigService    *service.ConfigService
+	EventService     *service.EventService
+	TicketService    *service.TicketService
+	StatsService     *service.StatsService
+	StepService      *service.TicketStepService
+	MetadataService  *service.MetadataService
+	UserTokenService *service.UserTokenService
 }
 
 // NewSmartElf 创建新的SmartElf实例
 func NewSmartElf(
 	configService *service.ConfigService,
 	eventService *service.EventService,
+	ticketService *service.TicketService,
+	statsService *service.StatsService,
+	stepService *service.TicketStepService,
+	metadataService *service.MetadataService,
+	userTokenService *service.UserTokenService,
 ) *SmartElf {
 	return &SmartElf{
-		ConfigService: configService,
-		EventService:  eventService,
+		ConfigService:    configService,
+		EventService:     eventService,
+		TicketService:    ticketService,
+		StatsService:     statsService,
+		StepService:      stepService,
+		MetadataService:  metadataService,
+		UserTokenService: userTokenService,
 	}
 }
 
 // HandleLarkEvent 处理飞书事件回调
-func (e *SmartElf) HandleLarkEvent(req *model.LarkCallbackRequest) (*model.LarkCallbackResponse, error) {
+func (e *SmartElf) HandleLarkEvent(ctx context.Context, req *model.LarkCallbackRequest) (*model.LarkCallbackResponse, error) {
 	// 处理URL验证
 	if req.Type == "url_verification" {
 		log.Printf("信息: 处理URL验证请求: type=url_verification")
@@ -41,7 +58,7 @@ func (e *SmartElf) HandleLarkEvent(req *model.LarkCallbackRequest) (*model.LarkC
 		log.Printf("信息: 处理消息接收事件: event_type=im.message.receive_v1")
 
 		// 交给EventService处理具体的事件逻辑
-		err := e.EventService.HandleMessageEvent(req)
+		err := e.EventService.HandleMessageEvent(ctx, req)
 		if err != nil {
 			log.Printf("错误: 处理消息事件失败: %v", err)
 			return nil, err
@@ -84,3 +101,148 @@ func (e *SmartElf) QueryConfig(projectKey string) (*model.ConfigResponse, error)
 	}
 	return config, nil
 }
+
+// TestConfig 测试插件配置的整条链路
+func (e *SmartElf) TestConfig(ctx context.Context, req *model.TestConfigRequest) (*model.TestConfigResponse, error) {
+	resp, err := e.EventService.TestConnection(ctx, req)
+	if err != nil {
+		log.Printf("错误: 测试配置失败: %v, project_key=%s", err, req.ProjectKey)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetConfigEnabled 启用或停用插件配置
+func (e *SmartElf) SetConfigEnabled(projectKey string, enabled bool) error {
+	err := e.ConfigService.SetEnabled(projectKey, enabled)
+	if err != nil {
+		log.Printf("错误: 更新启用状态失败: %v, project_key=%s", err, projectKey)
+		return err
+	}
+	return nil
+}
+
+// DeleteConfig 删除插件配置
+func (e *SmartElf) DeleteConfig(projectKey string) error {
+	err := e.ConfigService.DeleteConfig(projectKey)
+	if err != nil {
+		log.Printf("错误: 删除配置失败: %v, project_key=%s", err, projectKey)
+		return err
+	}
+	return nil
+}
+
+// HandleMeegoEvent 处理飞书项目 Webhook 事件
+func (e *SmartElf) HandleMeegoEvent(ctx context.Context, signature string, req *model.MeegoEventRequest) error {
+	eventType := ""
+	if req.Header != nil {
+		eventType = req.Header.EventType
+	}
+	log.Printf("信息: 处理飞书项目事件: event_type=%s", eventType)
+
+	err := e.EventService.HandleWorkItemEvent(ctx, signature, req)
+	if err != nil {
+		log.Printf("错误: 处理飞书项目事件失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ListTickets 分页查询工单台账
+func (e *SmartElf) ListTickets(req *model.TicketListRequest) (*model.TicketListResponse, error) {
+	resp, err := e.TicketService.ListTickets(req)
+	if err != nil {
+		log.Printf("错误: 查询工单台账失败: %v, project_key=%s", err, req.ProjectKey)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GetTicket 查询单条工单台账
+func (e *SmartElf) GetTicket(projectKey string, id uint) (*model.Ticket, error) {
+	ticket, err := e.TicketService.GetTicket(projectKey, id)
+	if err != nil {
+		log.Printf("错误: 查询工单失败: %v, project_key=%s, id=%d", err, projectKey, id)
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// TicketStats 统计工单数据，refresh 时先从飞书项目同步工单状态
+func (e *SmartElf) TicketStats(ctx context.Context, req *model.TicketStatsRequest) (*model.TicketStatsResponse, error) {
+	if req.Refresh {
+		if err := e.EventService.SyncTicketStates(ctx, req.ProjectKey); err != nil {
+			log.Printf("错误: 同步工单状态失败: %v, project_key=%s", err, req.ProjectKey)
+		}
+	}
+	stats, err := e.StatsService.GetTicketStats(req)
+	if err != nil {
+		log.Printf("错误: 统计工单失败: %v, project_key=%s", err, req.ProjectKey)
+		return nil, err
+	}
+	return stats, nil
+}
+
+// TicketStatsCSV 将工单统计结果导出为 CSV
+func (e *SmartElf) TicketStatsCSV(stats *model.TicketStatsResponse) ([]byte, error) {
+	return e.StatsService.TicketStatsCSV(stats)
+}
+
+// ListTicketSteps 分页查询工单后续步骤
+func (e *SmartElf) ListTicketSteps(req *model.TicketStepListRequest) (*model.TicketStepListResponse, error) {
+	resp, err := e.StepService.ListSteps(req)
+	if err != nil {
+		log.Printf("错误: 查询工单后续步骤失败: %v, project_key=%s", err, req.ProjectKey)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReplayTicketSteps 重放失败的工单后续步骤
+func (e *SmartElf) ReplayTicketSteps(ctx context.Context, req *model.TicketStepReplayRequest) ([]*model.TicketStep, error) {
+	steps, err := e.EventService.ReplaySteps(ctx, req)
+	if err != nil {
+		log.Printf("错误: 重放工单后续步骤失败: %v, project_key=%s", err, req.ProjectKey)
+		return nil, err
+	}
+	return steps, nil
+}
+
+// GetProjectMetadata 查询空间元数据（优先读缓存）
+func (e *SmartElf) GetProjectMetadata(ctx context.Context, req *model.ProjectMetadataRequest) (*model.ProjectMetadataResponse, error) {
+	resp, err := e.MetadataService.GetMetadata(ctx, req)
+	if err != nil {
+		log.Printf("错误: 查询空间元数据失败: %v, project_key=%s", err, req.ProjectKey)
+		return nil, err
+	}
+	return resp, nil
+}
+
+// InvalidateProjectMetadata 清除空间元数据缓存
+func (e *SmartElf) InvalidateProjectMetadata(projectKey string) {
+	e.MetadataService.Invalidate(projectKey)
+}
+
+// AuthorizeProject 校验用户能否访问空间，manage 为 true 时要求为空间管理员，无权时返回 service.ErrProjectForbidden
+func (e *SmartElf) AuthorizeProject(ctx context.Context, projectKey, userKey string, manage bool) error {
+	err := e.MetadataService.AuthorizeProject(ctx, projectKey, userKey, manage)
+	if err != nil && !errors.Is(err, service.ErrProjectForbidden) {
+		log.Printf("错误: 校验空间权限失败: %v, project_key=%s, user_key=%s", err, projectKey, userKey)
+	}
+	return err
+}
+
+// AuthorizeUser 使用插件授权码换取并保存用户凭证
+func (e *SmartElf) AuthorizeUser(ctx context.Context, code string) (*auth.UserToken, error) {
+	token, err := e.UserTokenService.Authorize(ctx, code)
+	if err != nil {
+		log.Printf("错误: 用户授权失败: %v", err)
+		return nil, err
+	}
+	return token, nil
+}
+
+// UserAccessToken 获取已授权用户的访问令牌，未授权时返回 service.ErrUserNotAuthorized
+func (e *SmartElf) UserAccessToken(ctx context.Context, userKey string) (string, error) {
+	return e.UserTokenService.AccessToken(ctx, userKey)
+}
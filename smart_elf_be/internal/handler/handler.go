@@ -1,10 +1,14 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"smart_elf_standalone/internal"
 	"smart_elf_standalone/internal/model"
+	"smart_elf_standalone/internal/service"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin" // 确保 go.mod 中已添加依赖: go get -u github.com/gin-gonic/gin
@@ -32,7 +36,7 @@ func (h *Handler) HandleLarkEvent(c *gin.Context) {
 	}
 
 	// 调用SmartElf处理事件
-	resp, err := h.smartElf.HandleLarkEvent(&req)
+	resp, err := h.smartElf.HandleLarkEvent(c.Request.Context(), &req)
 	if err != nil {
 		log.Printf("错误: 处理飞书事件失败: %v", err)
 		Error(c, http.StatusInternalServerError, "Failed to handle event")
@@ -42,6 +46,24 @@ func (h *Handler) HandleLarkEvent(c *gin.Context) {
 	Success(c, resp)
 }
 
+// HandleMeegoEvent 处理飞书项目 Webhook 事件，签名通过 signature 查询参数传入
+func (h *Handler) HandleMeegoEvent(c *gin.Context) {
+	var req model.MeegoEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.smartElf.HandleMeegoEvent(c.Request.Context(), c.Query("signature"), &req); err != nil {
+		log.Printf("错误: 处理飞书项目事件失败: %v", err)
+		Error(c, http.StatusInternalServerError, "Failed to handle event")
+		return
+	}
+
+	Success(c, nil)
+}
+
 // GetSignature 获取插件签名
 func (h *Handler) GetSignature(c *gin.Context) {
 	var req model.SignatureRequest
@@ -101,6 +123,256 @@ func (h *Handler) QueryConfig(c *gin.Context) {
 	Success(c, config)
 }
 
+// TestConfig 测试插件配置连通性，调用方需携带插件会话
+func (h *Handler) TestConfig(c *gin.Context) {
+	var req model.TestConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	req.UserKey = c.GetString(sessionUserKey)
+
+	resp, err := h.smartElf.TestConfig(c.Request.Context(), &req)
+	if err != nil {
+		log.Printf("错误: 测试配置失败: project_key=%s, err=%v", req.ProjectKey, err)
+		Error(c, http.StatusInternalServerError, "Failed to test config")
+		return
+	}
+
+	Success(c, resp)
+}
+
+// authorizeProject 校验会话用户能否访问空间，manage 为 true 时要求为空间管理员；校验不通过时写入错误响应并返回 false
+func (h *Handler) authorizeProject(c *gin.Context, projectKey string, manage bool) bool {
+	userKey := c.GetString(sessionUserKey)
+	err := h.smartElf.AuthorizeProject(c.Request.Context(), projectKey, userKey, manage)
+	if err == nil {
+		return true
+	}
+	if errors.Is(err, service.ErrProjectForbidden) {
+		log.Printf("警告: 无权访问空间: project_key=%s, user_key=%s, manage=%v, path=%s", projectKey, userKey, manage, c.Request.URL.Path)
+		Error(c, http.StatusForbidden, "Forbidden")
+		return false
+	}
+	Error(c, http.StatusBadGateway, "Failed to check project permission")
+	return false
+}
+
+// EnableConfig 启用机器人连接，调用方需为空间管理员
+func (h *Handler) EnableConfig(c *gin.Context) {
+	h.setConfigEnabled(c, true)
+}
+
+// DisableConfig 停用机器人连接，调用方需为空间管理员
+func (h *Handler) DisableConfig(c *gin.Context) {
+	h.setConfigEnabled(c, false)
+}
+
+// setConfigEnabled 更新机器人连接的启用状态
+func (h *Handler) setConfigEnabled(c *gin.Context, enabled bool) {
+	var req model.ConfigStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !h.authorizeProject(c, req.ProjectKey, true) {
+		return
+	}
+
+	err := h.smartElf.SetConfigEnabled(req.ProjectKey, enabled)
+	if err != nil {
+		log.Printf("错误: 更新启用状态失败: project_key=%s, err=%v", req.ProjectKey, err)
+		Error(c, http.StatusInternalServerError, "Failed to update config status")
+		return
+	}
+
+	Success(c, gin.H{"enabled": enabled})
+}
+
+// DeleteConfig 删除插件配置并吊销签名，调用方需为空间管理员
+func (h *Handler) DeleteConfig(c *gin.Context) {
+	var req model.ConfigStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !h.authorizeProject(c, req.ProjectKey, true) {
+		return
+	}
+
+	err := h.smartElf.DeleteConfig(req.ProjectKey)
+	if err != nil {
+		log.Printf("错误: 删除配置失败: project_key=%s, err=%v", req.ProjectKey, err)
+		Error(c, http.StatusInternalServerError, "Failed to delete config")
+		return
+	}
+
+	Success(c, gin.H{"message": "Config deleted successfully"})
+}
+
+// ListTickets 分页查询工单台账，调用方需为空间成员
+func (h *Handler) ListTickets(c *gin.Context) {
+	var req model.TicketListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	if !h.authorizeProject(c, req.ProjectKey, false) {
+		return
+	}
+
+	resp, err := h.smartElf.ListTickets(&req)
+	if err != nil {
+		log.Printf("错误: 查询工单台账失败: project_key=%s, err=%v", req.ProjectKey, err)
+		Error(c, http.StatusInternalServerError, "Failed to list tickets")
+		return
+	}
+
+	Success(c, resp)
+}
+
+// TicketStats 工单统计，format=csv 时导出 CSV，调用方需为空间成员
+func (h *Handler) TicketStats(c *gin.Context) {
+	var req model.TicketStatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	if !h.authorizeProject(c, req.ProjectKey, false) {
+		return
+	}
+
+	stats, err := h.smartElf.TicketStats(c.Request.Context(), &req)
+	if err != nil {
+		log.Printf("错误: 统计工单失败: project_key=%s, err=%v", req.ProjectKey, err)
+		Error(c, http.StatusInternalServerError, "Failed to get ticket stats")
+		return
+	}
+
+	if req.Format == "csv" {
+		data, err := h.smartElf.TicketStatsCSV(stats)
+		if err != nil {
+			log.Printf("错误: 导出工单统计失败: project_key=%s, err=%v", req.ProjectKey, err)
+			Error(c, http.StatusInternalServerError, "Failed to export ticket stats")
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=ticket_stats_%s.csv", req.ProjectKey))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", data)
+		return
+	}
+
+	Success(c, stats)
+}
+
+// GetTicket 查询单条工单台账，调用方需为空间成员
+func (h *Handler) GetTicket(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		Error(c, http.StatusBadRequest, "Invalid ticket id")
+		return
+	}
+	projectKey := c.Query("project_key")
+	if projectKey == "" {
+		log.Printf("错误: 缺少project_key参数")
+		Error(c, http.StatusBadRequest, "Missing project_key parameter")
+		return
+	}
+	if !h.authorizeProject(c, projectKey, false) {
+		return
+	}
+
+	ticket, err := h.smartElf.GetTicket(projectKey, uint(id))
+	if err != nil {
+		log.Printf("错误: 查询工单失败: project_key=%s, id=%d, err=%v", projectKey, id, err)
+		Error(c, http.StatusNotFound, "Ticket not found")
+		return
+	}
+
+	Success(c, ticket)
+}
+
+// ListTicketSteps 分页查询工单后续步骤，调用方需为空间成员
+func (h *Handler) ListTicketSteps(c *gin.Context) {
+	var req model.TicketStepListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	if !h.authorizeProject(c, req.ProjectKey, false) {
+		return
+	}
+
+	resp, err := h.smartElf.ListTicketSteps(&req)
+	if err != nil {
+		log.Printf("错误: 查询工单后续步骤失败: project_key=%s, err=%v", req.ProjectKey, err)
+		Error(c, http.StatusInternalServerError, "Failed to list ticket steps")
+		return
+	}
+
+	Success(c, resp)
+}
+
+// ReplayTicketSteps 重放失败的工单后续步骤，调用方需为空间管理员
+func (h *Handler) ReplayTicketSteps(c *gin.Context) {
+	var req model.TicketStepReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if !h.authorizeProject(c, req.ProjectKey, true) {
+		return
+	}
+
+	steps, err := h.smartElf.ReplayTicketSteps(c.Request.Context(), &req)
+	if err != nil {
+		log.Printf("错误: 重放工单后续步骤失败: project_key=%s, err=%v", req.ProjectKey, err)
+		Error(c, http.StatusInternalServerError, "Failed to replay ticket steps")
+		return
+	}
+
+	Success(c, steps)
+}
+
+// GetProjectMetadata 查询空间元数据（空间信息、工作项类型、模板与字段），以会话中的用户身份查询
+func (h *Handler) GetProjectMetadata(c *gin.Context) {
+	var req model.ProjectMetadataRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid query parameters")
+		return
+	}
+	req.UserKey = c.GetString(sessionUserKey)
+
+	resp, err := h.smartElf.GetProjectMetadata(c.Request.Context(), &req)
+	if err != nil {
+		log.Printf("错误: 查询空间元数据失败: project_key=%s, err=%v", req.ProjectKey, err)
+		Error(c, http.StatusInternalServerError, "Failed to get project metadata")
+		return
+	}
+
+	Success(c, resp)
+}
+
+// InvalidateProjectMetadata 清除空间元数据缓存
+func (h *Handler) InvalidateProjectMetadata(c *gin.Context) {
+	var req model.MetadataInvalidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("错误: 绑定请求参数失败: %v", err)
+		Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	h.smartElf.InvalidateProjectMetadata(req.ProjectKey)
+	Success(c, nil)
+}
+
 // HealthCheck 健康检查
 func (h *Handler) HealthCheck(c *gin.Context) {
 	Success(c, gin.H{
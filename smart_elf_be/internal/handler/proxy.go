@@ -1,46 +1,283 @@
 package handler
 
 import (
+	"context"
+	"errors"
+	"log"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"smart_elf_standalone/internal"
 	"smart_elf_standalone/internal/auth"
+	"smart_elf_standalone/internal/model"
+	"smart_elf_standalone/internal/service"
+	"smart_elf_standalone/pkg/config"
 
 	"github.com/gin-gonic/gin"
 )
 
+// ProxyHandler 飞书项目 OpenAPI 代理，仅转发白名单内的接口。
+// 调用方已授权时使用其用户令牌，否则附带插件令牌并按调用方会话注入 X-User-Key
 type ProxyHandler struct {
-	target     *url.URL
+	// target 转发目标地址，配置热更新时整体替换
+	target     atomic.Pointer[url.URL]
+	proxy      *httputil.ReverseProxy
+	smartElf   *internal.SmartElf
 	feishuAuth *auth.FeishuAuth
+	sessions   *auth.SessionManager
+	rules      []proxyRule
 }
 
-func NewProxyHandler(target string, feishuAuth *auth.FeishuAuth) *ProxyHandler {
+// proxyCall 单次代理请求的上下文信息，经请求 context 传递给共享的反向代理
+type proxyCall struct {
+	userKey string
+	path    string
+	token   string
+}
+
+type proxyCallKey struct{}
+
+// sessionUserKey 上下文中保存会话 user_key 的键
+const sessionUserKey = "session_user_key"
+
+// 代理上游连接的默认配置
+const (
+	defaultProxyDialTimeout           = 5 * time.Second
+	defaultProxyKeepAlive             = 30 * time.Second
+	defaultProxyTLSHandshakeTimeout   = 5 * time.Second
+	defaultProxyResponseHeaderTimeout = 15 * time.Second
+	defaultProxyIdleConnTimeout       = 90 * time.Second
+	defaultProxyMaxIdleConns          = 100
+	defaultProxyMaxIdleConnsPerHost   = 20
+)
+
+// proxyRule 规范化后的白名单规则
+type proxyRule struct {
+	methods map[string]bool
+	pattern string
+}
+
+// NewProxyHandler 创建代理处理器
+func NewProxyHandler(target string, smartElf *internal.SmartElf, feishuAuth *auth.FeishuAuth, cfg config.ProxyConfig) *ProxyHandler {
+	rules := make([]proxyRule, 0, len(cfg.Allow))
+	for _, r := range cfg.Allow {
+		if _, err := path.Match(r.Path, ""); err != nil {
+			panic("invalid proxy path pattern: " + r.Path)
+		}
+		methods := make(map[string]bool)
+		for _, m := range r.Methods {
+			methods[strings.ToUpper(m)] = true
+		}
+		if len(methods) == 0 {
+			methods[http.MethodGet] = true
+		}
+		rules = append(rules, proxyRule{methods: methods, pattern: r.Path})
+	}
+	h := &ProxyHandler{
+		smartElf:   smartElf,
+		feishuAuth: feishuAuth,
+		sessions:   auth.NewSessionManager(cfg.SessionSecret, time.Duration(cfg.SessionTTLMinutes)*time.Minute),
+		rules:      rules,
+	}
+	if err := h.SetTarget(target); err != nil {
+		panic(err)
+	}
+	h.proxy = &httputil.ReverseProxy{
+		Rewrite:      h.rewrite,
+		Transport:    newProxyTransport(cfg.Transport),
+		ErrorHandler: h.handleError,
+	}
+	return h
+}
+
+// SetTarget 替换转发目标地址，进行中的请求不受影响
+func (h *ProxyHandler) SetTarget(target string) error {
 	targetURL, err := url.Parse(target)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	return &ProxyHandler{
-		target:     targetURL,
-		feishuAuth: feishuAuth,
+	if old := h.target.Swap(targetURL); old != nil && old.String() != targetURL.String() {
+		log.Printf("信息: 代理目标地址已更新: %s", targetURL)
+	}
+	return nil
+}
+
+// newProxyTransport 按配置创建上游连接
+func newProxyTransport(cfg config.ProxyTransportConfig) *http.Transport {
+	proxyFunc := http.ProxyFromEnvironment
+	if cfg.UpstreamProxy != "" {
+		upstream, err := url.Parse(cfg.UpstreamProxy)
+		if err != nil {
+			panic(err)
+		}
+		proxyFunc = http.ProxyURL(upstream)
+	}
+	dialer := &net.Dialer{
+		Timeout:   secondsOr(cfg.DialTimeoutSeconds, defaultProxyDialTimeout),
+		KeepAlive: secondsOr(cfg.KeepAliveSeconds, defaultProxyKeepAlive),
+	}
+	return &http.Transport{
+		Proxy:                 proxyFunc,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   secondsOr(cfg.TLSHandshakeTimeoutSeconds, defaultProxyTLSHandshakeTimeout),
+		ResponseHeaderTimeout: secondsOr(cfg.ResponseHeaderTimeoutSeconds, defaultProxyResponseHeaderTimeout),
+		IdleConnTimeout:       secondsOr(cfg.IdleConnTimeoutSeconds, defaultProxyIdleConnTimeout),
+		MaxIdleConns:          intOr(cfg.MaxIdleConns, defaultProxyMaxIdleConns),
+		MaxIdleConnsPerHost:   intOr(cfg.MaxIdleConnsPerHost, defaultProxyMaxIdleConnsPerHost),
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// CreateSession 使用插件授权码换取并保存用户凭证，返回代理会话
+func (h *ProxyHandler) CreateSession(c *gin.Context) {
+	var req model.PluginSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		Error(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	userToken, err := h.smartElf.AuthorizeUser(c.Request.Context(), req.Code)
+	if err != nil {
+		log.Printf("错误: 授权码换取用户凭证失败: %v, client_ip=%s", err, c.ClientIP())
+		if errors.Is(err, auth.ErrInvalidAuthCode) {
+			Error(c, http.StatusUnauthorized, "Invalid auth code")
+			return
+		}
+		Error(c, http.StatusBadGateway, "Failed to exchange auth code")
+		return
 	}
+	session, expireAt := h.sessions.Issue(userToken.UserKey)
+	log.Printf("信息: 代理会话已创建: user_key=%s", userToken.UserKey)
+	Success(c, &model.PluginSessionResponse{
+		Session:  session,
+		UserKey:  userToken.UserKey,
+		ExpireAt: expireAt.Unix(),
+	})
 }
 
+// ProxyRequest 校验调用方会话与白名单后转发请求
 func (h *ProxyHandler) ProxyRequest(c *gin.Context) {
-	proxy := httputil.NewSingleHostReverseProxy(h.target)
-	proxy.Director = func(req *http.Request) {
-		req.Host = h.target.Host
-		req.URL.Scheme = "https"
-		req.URL.Host = h.target.Host
-		req.URL.Path = c.Param("path")
-
-		token, err := h.feishuAuth.GetToken()
+	reqPath := path.Clean("/" + c.Param("path"))
+
+	userKey, err := h.authenticate(c)
+	if err != nil {
+		log.Printf("警告: 代理请求鉴权失败: client_ip=%s, method=%s, path=%s", c.ClientIP(), c.Request.Method, reqPath)
+		Error(c, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if !h.allowed(c.Request.Method, reqPath) {
+		log.Printf("警告: 代理请求不在白名单内: user_key=%s, method=%s, path=%s", userKey, c.Request.Method, reqPath)
+		Error(c, http.StatusForbidden, "Path not allowed")
+		return
+	}
+
+	token, err := h.accessToken(c, userKey)
+	if err != nil {
+		log.Printf("错误: 获取插件令牌失败: %v, user_key=%s, path=%s", err, userKey, reqPath)
+		if auth.IsAuthError(err) {
+			Error(c, http.StatusBadGateway, "Plugin credentials rejected")
+			return
+		}
+		Error(c, http.StatusBadGateway, "Failed to get plugin token")
+		return
+	}
+	log.Printf("信息: 代理请求: user_key=%s, method=%s, path=%s", userKey, c.Request.Method, reqPath)
+
+	call := &proxyCall{userKey: userKey, path: reqPath, token: token}
+	ctx := context.WithValue(c.Request.Context(), proxyCallKey{}, call)
+	h.proxy.ServeHTTP(c.Writer, c.Request.WithContext(ctx))
+}
+
+// accessToken 优先使用调用方的用户令牌，未授权时使用插件令牌
+func (h *ProxyHandler) accessToken(c *gin.Context, userKey string) (string, error) {
+	token, err := h.smartElf.UserAccessToken(c.Request.Context(), userKey)
+	if err == nil {
+		return token, nil
+	}
+	if !errors.Is(err, service.ErrUserNotAuthorized) {
+		log.Printf("警告: 获取用户令牌失败，改用插件令牌: %v, user_key=%s", err, userKey)
+	}
+	return h.feishuAuth.GetToken()
+}
+
+// rewrite 将请求转发到目标地址，保留目标的协议与路径前缀
+func (h *ProxyHandler) rewrite(pr *httputil.ProxyRequest) {
+	call := pr.In.Context().Value(proxyCallKey{}).(*proxyCall)
+	pr.Out.URL.Path = call.path
+	pr.Out.URL.RawPath = ""
+	pr.SetURL(h.target.Load())
+	pr.SetXForwarded()
+	// 调用方会话只用于访问本服务，不转发到上游；X-User-Key 以会话中的用户为准，忽略调用方传入的值
+	pr.Out.Header.Del("Authorization")
+	pr.Out.Header.Set("X-Plugin-Token", call.token)
+	pr.Out.Header.Set("X-User-Key", call.userKey)
+}
+
+// handleError 上游请求失败时按标准格式返回 502
+func (h *ProxyHandler) handleError(w http.ResponseWriter, req *http.Request, err error) {
+	call, _ := req.Context().Value(proxyCallKey{}).(*proxyCall)
+	if call == nil {
+		call = &proxyCall{}
+	}
+	log.Printf("错误: 代理请求上游失败: %v, user_key=%s, path=%s", err, call.userKey, call.path)
+	writeError(w, http.StatusBadGateway, "Upstream request failed")
+}
+
+// RequireSession 要求请求携带有效的插件会话，会话中的 user_key 存入上下文供后续处理器使用
+func (h *ProxyHandler) RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userKey, err := h.authenticate(c)
 		if err != nil {
-			// Handle error, maybe return an error to the client
+			log.Printf("警告: 接口鉴权失败: client_ip=%s, method=%s, path=%s", c.ClientIP(), c.Request.Method, c.Request.URL.Path)
+			Error(c, http.StatusUnauthorized, "Unauthorized")
+			c.Abort()
 			return
 		}
-		req.Header.Set("X-Plugin-Token", token)
+		c.Set(sessionUserKey, userKey)
+		c.Next()
 	}
+}
 
-	proxy.ServeHTTP(c.Writer, c.Request)
+// authenticate 校验调用方会话，返回会话中的用户 user_key
+func (h *ProxyHandler) authenticate(c *gin.Context) (string, error) {
+	session, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || session == "" {
+		return "", auth.ErrInvalidSession
+	}
+	return h.sessions.Verify(session)
+}
+
+// allowed 请求方法与路径是否命中白名单
+func (h *ProxyHandler) allowed(method, reqPath string) bool {
+	for _, r := range h.rules {
+		if !r.methods[method] {
+			continue
+		}
+		if ok, _ := path.Match(r.pattern, reqPath); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// secondsOr 将秒数转换为时长，非正数时使用默认值
+func secondsOr(seconds int, def time.Duration) time.Duration {
+	if seconds <= 0 {
+		return def
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// intOr 非正数时使用默认值
+func intOr(v, def int) int {
+	if v <= 0 {
+		return def
+	}
+	return v
 }
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -28,3 +29,13 @@ func Error(c *gin.Context, code int, msg string) {
 		ErrMsg:  msg,
 	})
 }
+
+// writeError 在无 gin.Context 的场景（如反向代理回调）按标准格式响应失败
+func writeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(Response{
+		ErrCode: code,
+		ErrMsg:  msg,
+	})
+}
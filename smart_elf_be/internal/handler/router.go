@@ -2,13 +2,14 @@ package handler
 
 import (
     "smart_elf_standalone/internal/auth"
+    "smart_elf_standalone/pkg/config"
 
     "github.com/gin-gonic/gin"
     "github.com/rs/zerolog/log"
 )
 
 // SetupRouter 设置路由
-func SetupRouter(h *Handler, feishuAuth *auth.FeishuAuth, projectWebHost string) *gin.Engine {
+func SetupRouter(h *Handler, feishuAuth *auth.FeishuAuth, projectWebHost string, proxyCfg config.ProxyConfig) *gin.Engine {
 	// 创建Gin引擎
 	router := gin.Default()
 
@@ -18,7 +19,12 @@ func SetupRouter(h *Handler, feishuAuth *auth.FeishuAuth, projectWebHost string)
 
     // 健康检查
     router.GET("/health", h.HealthCheck)
-    proxyHandler := NewProxyHandler(projectWebHost, feishuAuth)
+    proxyHandler := NewProxyHandler(projectWebHost, h.smartElf, feishuAuth, proxyCfg)
+    config.OnReload(func(cfg *config.Config) {
+        if err := proxyHandler.SetTarget(cfg.Feishu.ProjectWebHost); err != nil {
+            log.Error().Err(err).Msg("更新代理目标地址失败")
+        }
+    })
 
 	// API路由组
 	api := router.Group("/api/v1")
@@ -26,12 +32,39 @@ func SetupRouter(h *Handler, feishuAuth *auth.FeishuAuth, projectWebHost string)
 		// 飞书事件回调
 		api.POST("/lark/event", h.HandleLarkEvent)
 
+		// 飞书项目事件回调
+		api.POST("/meego/event", h.HandleMeegoEvent)
+
 		// 配置管理
 		config := api.Group("/config")
 		{
 			config.POST("/update", h.UpdateConfig)
 			config.GET("/query", h.QueryConfig)
 			config.POST("/signature", h.GetSignature)
+			config.POST("/test", proxyHandler.RequireSession(), h.TestConfig)
+			config.POST("/enable", proxyHandler.RequireSession(), h.EnableConfig)
+			config.POST("/disable", proxyHandler.RequireSession(), h.DisableConfig)
+			config.POST("/delete", proxyHandler.RequireSession(), h.DeleteConfig)
+		}
+
+		// 工单台账
+		tickets := api.Group("/tickets")
+		{
+			tickets.GET("", proxyHandler.RequireSession(), h.ListTickets)
+			tickets.GET("/stats", proxyHandler.RequireSession(), h.TicketStats)
+			tickets.GET("/steps", proxyHandler.RequireSession(), h.ListTicketSteps)
+			tickets.POST("/steps/replay", proxyHandler.RequireSession(), h.ReplayTicketSteps)
+			tickets.GET("/:id", proxyHandler.RequireSession(), h.GetTicket)
+		}
+
+		// 代理会话
+		api.POST("/auth/session", proxyHandler.CreateSession)
+
+		// 空间元数据
+		metadata := api.Group("/metadata")
+		{
+			metadata.GET("", proxyHandler.RequireSession(), h.GetProjectMetadata)
+			metadata.POST("/invalidate", proxyHandler.RequireSession(), h.InvalidateProjectMetadata)
 		}
 	}
 	router.Any("/proxy/*path", proxyHandler.ProxyRequest)
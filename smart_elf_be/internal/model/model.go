@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -10,7 +12,7 @@ type AppConfig struct {
 	BotID                string `gorm:"column:bot_id" json:"bot_id"`
 	BotSecret            string `gorm:"column:bot_secret" json:"bot_secret"`
 	BotVerificationToken string `gorm:"column:bot_verification_token" json:"bot_verification_token"`
-	ProjectKey           string `gorm:"column:project_key" json:"project_key"`
+	ProjectKey           string `gorm:"column:project_key;size:255;uniqueIndex" json:"project_key"`
 	TenantKey            string `gorm:"column:tenant_key" json:"tenant_key"`
 	WorkItemTypeKey      string `gorm:"column:work_item_type_key" json:"work_item_type_key"`
 	WorkItemAPIName      string `gorm:"column:work_item_api_name" json:"work_item_api_name"`
@@ -18,8 +20,14 @@ type AppConfig struct {
 	CreatorFieldKey      string `gorm:"column:creator_field_key" json:"creator_field_key"`
 	ReplySwitch          bool   `gorm:"column:reply_switch" json:"reply_switch"`
 	CreateGroupSwitch    bool   `gorm:"column:create_group_switch" json:"create_group_switch"`
-	Signature            string `gorm:"column:signature" json:"signature"`
+	Signature            string `gorm:"column:signature;size:255;uniqueIndex" json:"signature"`
 	APIUserKey           string `gorm:"column:api_user_key" json:"api_user_key"`
+	Enabled              bool   `gorm:"column:enabled;default:true" json:"enabled"`
+	GroupNameTemplate    string `gorm:"column:group_name_template" json:"group_name_template"`
+	GroupRoleKey         string `gorm:"column:group_role_key" json:"group_role_key"`
+	GroupOnCallOpenIDs   string `gorm:"column:group_oncall_open_ids" json:"group_oncall_open_ids"`
+	GroupInviteMentions  bool   `gorm:"column:group_invite_mentions" json:"group_invite_mentions"`
+	AlertChatID          string `gorm:"column:alert_chat_id" json:"alert_chat_id"`
 }
 
 // TableName 指定表名
@@ -27,6 +35,342 @@ func (a AppConfig) TableName() string {
 	return "smart_elf"
 }
 
+// RateLimitBucket 工单创建与聊天指令限流令牌桶
+type RateLimitBucket struct {
+	ID         uint      `gorm:"primarykey"`
+	BucketKey  string    `gorm:"column:bucket_key;size:255;uniqueIndex" json:"bucket_key"`
+	Tokens     float64   `gorm:"column:tokens" json:"tokens"`
+	RefilledAt time.Time `gorm:"column:refilled_at" json:"refilled_at"`
+}
+
+// TableName 指定表名
+func (r RateLimitBucket) TableName() string {
+	return "smart_elf_rate_limit_bucket"
+}
+
+// TicketIndexEntry 近期工单标题索引，用于重复工单检测
+type TicketIndexEntry struct {
+	ID              uint      `gorm:"primarykey"`
+	CreatedAt       time.Time `gorm:"index"`
+	ProjectKey      string    `gorm:"column:project_key;size:255;index" json:"project_key"`
+	WorkItemTypeKey string    `gorm:"column:work_item_type_key" json:"work_item_type_key"`
+	WorkItemID      int64     `gorm:"column:work_item_id" json:"work_item_id"`
+	Title           string    `gorm:"column:title" json:"title"`
+	NormalizedTitle string    `gorm:"column:normalized_title" json:"normalized_title"`
+}
+
+// TableName 指定表名
+func (t TicketIndexEntry) TableName() string {
+	return "smart_elf_ticket_index"
+}
+
+// 工单群状态
+const (
+	TicketGroupStatusActive   = "active"
+	TicketGroupStatusResolved = "resolved"
+	TicketGroupStatusClosed   = "closed"
+)
+
+// TicketGroup 工单群与工作项的关联记录
+type TicketGroup struct {
+	gorm.Model
+	ProjectKey      string     `gorm:"column:project_key;size:255;index" json:"project_key"`
+	WorkItemTypeKey string     `gorm:"column:work_item_type_key" json:"work_item_type_key"`
+	WorkItemID      int64      `gorm:"column:work_item_id;index" json:"work_item_id"`
+	ChatID          string     `gorm:"column:chat_id;size:255;index" json:"chat_id"`
+	NameCN          string     `gorm:"column:name_cn" json:"name_cn"`
+	NameEN          string     `gorm:"column:name_en" json:"name_en"`
+	Status          string     `gorm:"column:status;size:32;index" json:"status"`
+	ResolvedAt      *time.Time `gorm:"column:resolved_at" json:"resolved_at"`
+	ClosedAt        *time.Time `gorm:"column:closed_at" json:"closed_at"`
+}
+
+// TableName 指定表名
+func (t TicketGroup) TableName() string {
+	return "smart_elf_ticket_group"
+}
+
+// 工单创建状态
+const (
+	TicketStatusPending = "pending"
+	TicketStatusCreated = "created"
+	TicketStatusFailed  = "failed"
+	TicketStatusMerged  = "merged"
+)
+
+// Ticket 工单台账，记录消息、工作项、群聊与提单人之间的关联
+type Ticket struct {
+	gorm.Model
+	ProjectKey      string `gorm:"column:project_key;size:255;index" json:"project_key"`
+	WorkItemTypeKey string `gorm:"column:work_item_type_key" json:"work_item_type_key"`
+	WorkItemID      int64  `gorm:"column:work_item_id;index" json:"work_item_id"`
+	Title           string `gorm:"column:title;type:text" json:"title"`
+	SourceMessageID string `gorm:"column:source_message_id;size:255;index" json:"source_message_id"`
+	SourceChatID    string `gorm:"column:source_chat_id;size:255;index" json:"source_chat_id"`
+	SourceChatType  string `gorm:"column:source_chat_type" json:"source_chat_type"`
+	SourceThreadID  string `gorm:"column:source_thread_id" json:"source_thread_id"`
+	ReporterOpenID  string `gorm:"column:reporter_open_id;size:255;index" json:"reporter_open_id"`
+	ReporterUnionID string `gorm:"column:reporter_union_id" json:"reporter_union_id"`
+	ReporterName    string `gorm:"column:reporter_name" json:"reporter_name"`
+	GroupChatID     string `gorm:"column:group_chat_id" json:"group_chat_id"`
+	Status          string `gorm:"column:status;size:32;index" json:"status"`
+	ErrMsg          string `gorm:"column:err_msg;type:text" json:"err_msg"`
+	// 以下时间同步自飞书项目的状态流转记录
+	FirstResponseAt *time.Time `gorm:"column:first_response_at" json:"first_response_at"`
+	ClosedAt        *time.Time `gorm:"column:closed_at" json:"closed_at"`
+}
+
+// TableName 指定表名
+func (t Ticket) TableName() string {
+	return "smart_elf_ticket"
+}
+
+// TicketListRequest 工单台账查询条件
+type TicketListRequest struct {
+	ProjectKey     string `form:"project_key" binding:"required"`
+	Status         string `form:"status"`
+	ReporterOpenID string `form:"reporter_open_id"`
+	SourceChatID   string `form:"source_chat_id"`
+	WorkItemID     int64  `form:"work_item_id"`
+	// 创建时间范围，Unix 秒
+	CreatedFrom int64 `form:"created_from"`
+	CreatedTo   int64 `form:"created_to"`
+	Page        int   `form:"page"`
+	PageSize    int   `form:"page_size"`
+}
+
+// TicketListResponse 工单台账分页结果
+type TicketListResponse struct {
+	Items    []*Ticket `json:"items"`
+	Total    int64     `json:"total"`
+	Page     int       `json:"page"`
+	PageSize int       `json:"page_size"`
+}
+
+// 工单创建后的后续步骤类型
+const (
+	TicketStepCreateGroup = "create_group"
+	TicketStepReply       = "reply"
+)
+
+// 后续步骤执行状态
+const (
+	TicketStepStatusPending   = "pending"
+	TicketStepStatusRunning   = "running"
+	TicketStepStatusRetrying  = "retrying"
+	TicketStepStatusSucceeded = "succeeded"
+	TicketStepStatusFailed    = "failed"
+)
+
+// TicketStep 工单创建后的后续步骤（拉群、回复提单人），失败后按退避策略重试
+type TicketStep struct {
+	gorm.Model
+	TicketID        uint       `gorm:"column:ticket_id;index" json:"ticket_id"`
+	ProjectKey      string     `gorm:"column:project_key;size:255;index" json:"project_key"`
+	WorkItemTypeKey string     `gorm:"column:work_item_type_key" json:"work_item_type_key"`
+	WorkItemID      int64      `gorm:"column:work_item_id;index" json:"work_item_id"`
+	StepType        string     `gorm:"column:step_type;size:32" json:"step_type"`
+	Status          string     `gorm:"column:status;size:32;index" json:"status"`
+	Attempts        int        `gorm:"column:attempts" json:"attempts"`
+	NextRetryAt     *time.Time `gorm:"column:next_retry_at;index" json:"next_retry_at"`
+	Payload         string     `gorm:"column:payload;type:text" json:"payload"`
+	Result          string     `gorm:"column:result" json:"result"`
+	ErrMsg          string     `gorm:"column:err_msg;type:text" json:"err_msg"`
+}
+
+// TableName 指定表名
+func (t TicketStep) TableName() string {
+	return "smart_elf_ticket_step"
+}
+
+// UserToken 飞书项目用户授权凭证，用户通过插件授权后以其身份调用飞书项目接口
+type UserToken struct {
+	gorm.Model
+	UserKey         string    `gorm:"column:user_key;size:255;uniqueIndex" json:"user_key"`
+	AccessToken     string    `gorm:"column:access_token;type:text" json:"-"`
+	ExpireAt        time.Time `gorm:"column:expire_at" json:"expire_at"`
+	RefreshToken    string    `gorm:"column:refresh_token;type:text" json:"-"`
+	RefreshExpireAt time.Time `gorm:"column:refresh_expire_at" json:"refresh_expire_at"`
+}
+
+// TableName 指定表名
+func (t UserToken) TableName() string {
+	return "smart_elf_user_token"
+}
+
+// TicketStepPayload 执行后续步骤所需的工单信息
+type TicketStepPayload struct {
+	Title          string   `json:"title"`
+	ReporterName   string   `json:"reporter_name"`
+	ReporterOpenID string   `json:"reporter_open_id"`
+	MentionOpenIDs []string `json:"mention_open_ids,omitempty"`
+}
+
+// TicketStepListRequest 后续步骤查询请求
+type TicketStepListRequest struct {
+	ProjectKey string `form:"project_key" binding:"required"`
+	Status     string `form:"status"`
+	WorkItemID int64  `form:"work_item_id"`
+	Page       int    `form:"page"`
+	PageSize   int    `form:"page_size"`
+}
+
+// TicketStepListResponse 后续步骤分页结果
+type TicketStepListResponse struct {
+	Items    []*TicketStep `json:"items"`
+	Total    int64         `json:"total"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+}
+
+// TicketStepReplayRequest 重放失败步骤请求，StepIDs 为空时重放该项目全部失败步骤
+type TicketStepReplayRequest struct {
+	ProjectKey string `json:"project_key" binding:"required"`
+	StepIDs    []uint `json:"step_ids"`
+}
+
+// 工单统计的时间粒度
+const (
+	StatsPeriodDay  = "day"
+	StatsPeriodWeek = "week"
+)
+
+// TicketStatsRequest 工单统计请求
+type TicketStatsRequest struct {
+	ProjectKey string `form:"project_key" binding:"required"`
+	// 统计时间范围，Unix 秒，默认最近30天
+	From   int64  `form:"from"`
+	To     int64  `form:"to"`
+	Period string `form:"period"`
+	// 输出格式: json 或 csv
+	Format string `form:"format"`
+	// 统计前先从飞书项目同步工单状态
+	Refresh bool `form:"refresh"`
+}
+
+// StatsBucket 单个统计维度值的计数
+type StatsBucket struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"`
+	Count int64  `json:"count"`
+}
+
+// DurationStats 耗时统计，单位秒
+type DurationStats struct {
+	Count         int64   `json:"count"`
+	AvgSeconds    float64 `json:"avg_seconds"`
+	MedianSeconds float64 `json:"median_seconds"`
+	P90Seconds    float64 `json:"p90_seconds"`
+}
+
+// TicketStatsResponse 工单统计结果。by_status 统计全部建单尝试（含失败、合并与处理中），
+// total 及其余维度只统计已创建的工单
+type TicketStatsResponse struct {
+	ProjectKey     string         `json:"project_key"`
+	From           int64          `json:"from"`
+	To             int64          `json:"to"`
+	Period         string         `json:"period"`
+	Total          int64          `json:"total"`
+	ByStatus       []*StatsBucket `json:"by_status"`
+	ByPeriod       []*StatsBucket `json:"by_period"`
+	ByChat         []*StatsBucket `json:"by_chat"`
+	ByReporter     []*StatsBucket `json:"by_reporter"`
+	ByWorkItemType []*StatsBucket `json:"by_work_item_type"`
+	FirstResponse  *DurationStats `json:"time_to_first_response"`
+	Close          *DurationStats `json:"time_to_close"`
+}
+
+// ProjectMetadataRequest 空间元数据查询请求
+type ProjectMetadataRequest struct {
+	ProjectKey      string `form:"project_key" binding:"required"`
+	WorkItemTypeKey string `form:"work_item_type_key"`
+	// 跳过缓存，直接从飞书项目拉取并刷新缓存
+	Refresh bool `form:"refresh"`
+	// UserKey 调用方的飞书项目 user_key，取自插件会话
+	UserKey string `form:"-"`
+}
+
+// MetadataInvalidateRequest 清除空间元数据缓存请求
+type MetadataInvalidateRequest struct {
+	ProjectKey string `json:"project_key" binding:"required"`
+}
+
+// ProjectMetadataResponse 空间元数据，指定工作项类型时包含模板与字段定义
+type ProjectMetadataResponse struct {
+	ProjectKey      string              `json:"project_key"`
+	Name            string              `json:"name"`
+	SimpleName      string              `json:"simple_name"`
+	WorkItemTypes   []*WorkItemTypeMeta `json:"work_item_types"`
+	WorkItemTypeKey string              `json:"work_item_type_key,omitempty"`
+	Templates       []*TemplateMeta     `json:"templates,omitempty"`
+	Fields          []*FieldMeta        `json:"fields,omitempty"`
+}
+
+// WorkItemTypeMeta 工作项类型
+type WorkItemTypeMeta struct {
+	TypeKey   string `json:"type_key"`
+	Name      string `json:"name"`
+	APIName   string `json:"api_name"`
+	IsDisable int32  `json:"is_disable"`
+}
+
+// TemplateMeta 工作项模板
+type TemplateMeta struct {
+	TemplateID   string `json:"template_id"`
+	TemplateName string `json:"template_name"`
+	IsDisabled   int32  `json:"is_disabled"`
+}
+
+// FieldMeta 工作项字段定义
+type FieldMeta struct {
+	FieldKey          string             `json:"field_key"`
+	FieldAlias        string             `json:"field_alias"`
+	FieldName         string             `json:"field_name"`
+	FieldTypeKey      string             `json:"field_type_key"`
+	IsCustomField     bool               `json:"is_custom_field"`
+	IsObsoleted       bool               `json:"is_obsoleted"`
+	ValueGenerateMode string             `json:"value_generate_mode"`
+	Options           []*FieldOptionMeta `json:"options,omitempty"`
+}
+
+// FieldOptionMeta 字段选项
+type FieldOptionMeta struct {
+	Label    string             `json:"label"`
+	Value    string             `json:"value"`
+	Children []*FieldOptionMeta `json:"children,omitempty"`
+}
+
+// PluginSessionRequest 插件会话创建请求，code 为前端 JSSDK 获取的授权码
+type PluginSessionRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// PluginSessionResponse 插件会话，调用代理时携带 Authorization: Bearer <session>
+type PluginSessionResponse struct {
+	Session  string `json:"session"`
+	UserKey  string `json:"user_key"`
+	ExpireAt int64  `json:"expire_at"`
+}
+
+// MeegoEventRequest 飞书项目 Webhook 事件
+type MeegoEventRequest struct {
+	Header  *MeegoEventHeader  `json:"header"`
+	Payload *MeegoEventPayload `json:"payload"`
+}
+
+// MeegoEventHeader 飞书项目事件头部
+type MeegoEventHeader struct {
+	EventType string `json:"event_type"`
+	Token     string `json:"token"`
+}
+
+// MeegoEventPayload 飞书项目事件中的工作项信息
+type MeegoEventPayload struct {
+	ID              int64  `json:"id"`
+	ProjectKey      string `json:"project_key"`
+	WorkItemTypeKey string `json:"work_item_type_key"`
+}
+
 // BotInfo 机器人信息
 type BotInfo struct {
 	BotID             string  `json:"bot_id" binding:"required"`
@@ -50,11 +394,26 @@ type Config struct {
 	ReplySwitch        bool    `json:"reply_switch"`
 	CreateGroupSwitch  bool    `json:"create_group_switch"`
 	APIUserKey         string  `json:"api_user_key"`
+	// 以下为可选配置，请求中未携带时保留原值
+	// 工单群名称模板，支持 {title}、{id}、{reporter} 占位符
+	GroupNameTemplate *string `json:"group_name_template"`
+	GroupRoleKey      *string `json:"group_role_key"`
+	// GroupOnCallOpenIDs 为 nil 表示未携带，空列表表示清空
+	GroupOnCallOpenIDs  []string `json:"group_oncall_open_ids"`
+	GroupInviteMentions *bool    `json:"group_invite_mentions"`
+	// 工单创建失败时通知的管理员群，为空时不通知
+	AlertChatID *string `json:"alert_chat_id"`
 }
 
 // ConfigResponse 配置响应结构
 type ConfigResponse struct {
-	Config *Config `json:"config"`
+	Config  *Config `json:"config"`
+	Enabled bool    `json:"enabled"`
+}
+
+// ConfigStatusRequest 启用、停用或删除配置的请求
+type ConfigStatusRequest struct {
+	ProjectKey string `json:"project_key" binding:"required"`
 }
 
 // SignatureRequest 签名请求
@@ -93,13 +452,25 @@ type LarkCallbackEvent struct {
 
 // LarkMessage 飞书消息
 type LarkMessage struct {
-	MessageID   string `json:"message_id"`
-	RootID      string `json:"root_id"`
-	ParentID    string `json:"parent_id"`
-	MsgType     string `json:"msg_type"`
-	Content     string `json:"content"`
-	CreateTime  string `json:"create_time"`
-	UpdatedTime string `json:"updated_time"`
+	MessageID   string         `json:"message_id"`
+	RootID      string         `json:"root_id"`
+	ParentID    string         `json:"parent_id"`
+	ChatID      string         `json:"chat_id"`
+	ChatType    string         `json:"chat_type"`
+	ThreadID    string         `json:"thread_id"`
+	Mentions    []*LarkMention `json:"mentions"`
+	MsgType     string         `json:"msg_type"`
+	Content     string         `json:"content"`
+	CreateTime  string         `json:"create_time"`
+	UpdatedTime string         `json:"updated_time"`
+}
+
+// LarkMention 飞书消息中的@信息
+type LarkMention struct {
+	Key       string        `json:"key"`
+	ID        *LarkSenderID `json:"id"`
+	Name      string        `json:"name"`
+	TenantKey string        `json:"tenant_key"`
 }
 
 // LarkSender 飞书发送者
@@ -125,3 +496,38 @@ type LarkCallbackResponse struct {
 type TextContent struct {
 	Text string `json:"text"`
 }
+
+// TestConfigRequest 连通性测试请求
+type TestConfigRequest struct {
+	ProjectKey string `json:"project_key" binding:"required"`
+	Message    string `json:"message" binding:"required"`
+	// SenderOpenID 模拟的消息发送者，实际创建工单时必填
+	SenderOpenID string `json:"sender_open_id"`
+	// Signature 飞书事件订阅地址中的 sig 参数，为空时使用当前配置的签名
+	Signature string `json:"signature"`
+	// DryRun 为空时默认只演练不创建工单
+	DryRun            *bool `json:"dry_run"`
+	DeleteAfterCreate bool  `json:"delete_after_create"`
+	// UserKey 调用方会话中的 user_key，实际创建的测试工单归属该用户
+	UserKey string `json:"-"`
+}
+
+// TestConfigStep 连通性测试的单个步骤结果
+type TestConfigStep struct {
+	Name    string `json:"name"`
+	Success bool   `json:"success"`
+	ErrMsg  string `json:"err_msg,omitempty"`
+}
+
+// TestConfigResponse 连通性测试响应
+type TestConfigResponse struct {
+	DryRun       bool              `json:"dry_run"`
+	Success      bool              `json:"success"`
+	Steps        []*TestConfigStep `json:"steps"`
+	ContentText  string            `json:"content_text,omitempty"`
+	ReporterName string            `json:"reporter_name,omitempty"`
+	Payload      interface{}       `json:"payload,omitempty"`
+	WorkItemID   int64             `json:"work_item_id,omitempty"`
+	WorkItemURL  string            `json:"work_item_url,omitempty"`
+	Deleted      bool              `json:"deleted"`
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"smart_elf_standalone/internal/auth"
+	"smart_elf_standalone/pkg/config"
+	"sync"
+	"time"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	projSDK "github.com/larksuite/project-oapi-sdk-golang"
+	"github.com/larksuite/project-oapi-sdk-golang/core"
+)
+
+// ClientRegistry 按机器人 bot_id 与插件 plugin_id 缓存飞书与飞书项目 SDK 客户端，可并发使用。
+// 同时持有当前生效的飞书配置，配置热更新后按新配置重建客户端
+type ClientRegistry struct {
+	mu             sync.RWMutex
+	feishuCfg      config.FeishuConfig
+	larkClients    map[string]*larkClientEntry
+	projectClients map[string]*projSDK.Client
+}
+
+// larkClientEntry 缓存的飞书客户端及创建时使用的凭证
+type larkClientEntry struct {
+	secret string
+	client *lark.Client
+}
+
+// NewClientRegistry 创建 SDK 客户端注册表
+func NewClientRegistry(feishuCfg config.FeishuConfig) *ClientRegistry {
+	return &ClientRegistry{
+		feishuCfg:      feishuCfg,
+		larkClients:    make(map[string]*larkClientEntry),
+		projectClients: make(map[string]*projSDK.Client),
+	}
+}
+
+// LarkClient 获取机器人的飞书客户端，凭证变化时重新创建
+func (r *ClientRegistry) LarkClient(botID, botSecret string) (*lark.Client, error) {
+	if botID == "" || botSecret == "" {
+		return nil, errors.New("invalid bot configuration")
+	}
+	r.mu.RLock()
+	entry, ok := r.larkClients[botID]
+	r.mu.RUnlock()
+	if ok && entry.secret == botSecret {
+		return entry.client, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.larkClients[botID]; ok && entry.secret == botSecret {
+		return entry.client, nil
+	}
+	// 每个客户端使用独立的令牌缓存，失效客户端时令牌一并丢弃
+	client := lark.NewClient(botID, botSecret,
+		lark.WithOpenBaseUrl(r.feishuCfg.IMOpenAPIHost),
+		lark.WithEnableTokenCache(true),
+		lark.WithTokenCache(newTokenCache()))
+	r.larkClients[botID] = &larkClientEntry{secret: botSecret, client: client}
+	return client, nil
+}
+
+// ProjectClient 获取插件的飞书项目客户端
+func (r *ClientRegistry) ProjectClient() (*projSDK.Client, error) {
+	r.mu.RLock()
+	pluginID := r.feishuCfg.PluginID
+	client, ok := r.projectClients[pluginID]
+	r.mu.RUnlock()
+	if ok {
+		return client, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pluginID = r.feishuCfg.PluginID
+	if pluginID == "" || r.feishuCfg.PluginSecret == "" {
+		return nil, errors.New("invalid plugin configuration")
+	}
+	if client, ok := r.projectClients[pluginID]; ok {
+		return client, nil
+	}
+	// 令牌类型已在启动时校验，此处不再处理错误
+	tokenType, _ := auth.ParseTokenType(r.feishuCfg.PluginToken.Type)
+	client = projSDK.NewClient(pluginID, r.feishuCfg.PluginSecret,
+		projSDK.WithOpenBaseUrl(r.feishuCfg.ProjectAPIHost),
+		projSDK.WithAccessTokenType(core.AccessTokenType(tokenType)),
+		projSDK.WithEnableTokenCache(true),
+		projSDK.WithTokenCache(newTokenCache()))
+	r.projectClients[pluginID] = client
+	return client, nil
+}
+
+// FeishuConfig 当前生效的飞书配置
+func (r *ClientRegistry) FeishuConfig() config.FeishuConfig {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.feishuCfg
+}
+
+// UpdateConfig 替换飞书配置，地址、插件凭证或令牌类型变化时丢弃已缓存的客户端，下次使用时按新配置重建。
+// 返回飞书项目的地址或插件凭证是否变化
+func (r *ClientRegistry) UpdateConfig(feishuCfg config.FeishuConfig) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old := r.feishuCfg
+	r.feishuCfg = feishuCfg
+	if old.IMOpenAPIHost != feishuCfg.IMOpenAPIHost {
+		r.larkClients = make(map[string]*larkClientEntry)
+		log.Printf("信息: 飞书开放平台地址已变更，飞书客户端将重建")
+	}
+	if old.ProjectAPIHost != feishuCfg.ProjectAPIHost || old.PluginID != feishuCfg.PluginID ||
+		old.PluginSecret != feishuCfg.PluginSecret || old.PluginToken.Type != feishuCfg.PluginToken.Type {
+		r.projectClients = make(map[string]*projSDK.Client)
+		log.Printf("信息: 飞书项目插件配置已变更，飞书项目客户端将重建")
+		return true
+	}
+	return false
+}
+
+// InvalidateLark 丢弃机器人的飞书客户端及其令牌缓存
+func (r *ClientRegistry) InvalidateLark(botID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.larkClients[botID]; ok {
+		delete(r.larkClients, botID)
+		log.Printf("信息: 飞书客户端已失效: bot_id=%s", botID)
+	}
+}
+
+// tokenCache 单个客户端的访问令牌缓存，同时满足飞书与飞书项目 SDK 的缓存接口
+type tokenCache struct {
+	m sync.Map
+}
+
+// tokenCacheValue 缓存的令牌及过期时间
+type tokenCacheValue struct {
+	value    string
+	expireAt time.Time
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{}
+}
+
+// Get 获取未过期的令牌，不存在时返回空字符串
+func (c *tokenCache) Get(ctx context.Context, key string) (string, error) {
+	if v, ok := c.m.Load(key); ok {
+		entry := v.(*tokenCacheValue)
+		if entry.expireAt.After(time.Now()) {
+			return entry.value, nil
+		}
+		c.m.Delete(key)
+	}
+	return "", nil
+}
+
+// Set 缓存令牌
+func (c *tokenCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.m.Store(key, &tokenCacheValue{value: value, expireAt: time.Now().Add(ttl)})
+	return nil
+}
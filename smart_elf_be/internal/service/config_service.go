@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log"
 	"smart_elf_standalone/internal/model"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,13 +16,15 @@ import (
 
 // ConfigService 配置服务
 type ConfigService struct {
-	db *gorm.DB
+	db             *gorm.DB
+	clientRegistry *ClientRegistry
 }
 
 // NewConfigService 创建配置服务实例
-func NewConfigService(db *gorm.DB) *ConfigService {
+func NewConfigService(db *gorm.DB, clientRegistry *ClientRegistry) *ConfigService {
 	return &ConfigService{
-		db: db,
+		db:             db,
+		clientRegistry: clientRegistry,
 	}
 }
 
@@ -58,9 +61,15 @@ func (s *ConfigService) UpdateConfig(req *model.ConfigRequest) error {
                 ReplySwitch:          req.Config.ReplySwitch,
                 CreateGroupSwitch:    req.Config.CreateGroupSwitch,
                 APIUserKey:           req.Config.APIUserKey,
+                Enabled:              true,
+                GroupNameTemplate:    getStringValue(req.Config.GroupNameTemplate, ""),
+                GroupRoleKey:         getStringValue(req.Config.GroupRoleKey, ""),
+                GroupOnCallOpenIDs:   strings.Join(req.Config.GroupOnCallOpenIDs, ","),
+                GroupInviteMentions:  getBoolValue(req.Config.GroupInviteMentions, false),
+                AlertChatID:          getStringValue(req.Config.AlertChatID, ""),
             }
 
-			if err := s.db.Create(&appConfig).Error; err != nil {
+			if err := s.createConfig(&appConfig); err != nil {
 				log.Printf("错误: 创建配置失败: %v", err)
 				return err
 			}
@@ -84,11 +93,31 @@ func (s *ConfigService) UpdateConfig(req *model.ConfigRequest) error {
             "api_user_key":           req.Config.APIUserKey,
             "updated_at":             time.Now(),
         }
+        // 可选配置仅在请求携带时更新，避免未提交这些字段的客户端清空已有配置
+        if req.Config.GroupNameTemplate != nil {
+            updates["group_name_template"] = *req.Config.GroupNameTemplate
+        }
+        if req.Config.GroupRoleKey != nil {
+            updates["group_role_key"] = *req.Config.GroupRoleKey
+        }
+        if req.Config.GroupOnCallOpenIDs != nil {
+            updates["group_oncall_open_ids"] = strings.Join(req.Config.GroupOnCallOpenIDs, ",")
+        }
+        if req.Config.GroupInviteMentions != nil {
+            updates["group_invite_mentions"] = *req.Config.GroupInviteMentions
+        }
+        if req.Config.AlertChatID != nil {
+            updates["alert_chat_id"] = *req.Config.AlertChatID
+        }
 
 		if err := s.db.Model(&appConfig).Updates(updates).Error; err != nil {
 			log.Printf("错误: 更新配置失败: %v", err)
 			return err
 		}
+		// 机器人凭证变化时丢弃缓存的客户端
+		if appConfig.BotID != req.Config.Bot.BotID || appConfig.BotSecret != req.Config.Bot.BotSecret {
+			s.clientRegistry.InvalidateLark(appConfig.BotID)
+		}
 		log.Printf("信息: 更新配置成功: project_key=%s", req.ProjectKey)
 	}
 
@@ -123,7 +152,13 @@ func (s *ConfigService) QueryConfig(projectKey string) (*model.ConfigResponse, e
             ReplySwitch:        appConfig.ReplySwitch,
             CreateGroupSwitch:  appConfig.CreateGroupSwitch,
             APIUserKey:         appConfig.APIUserKey,
+            GroupNameTemplate:   &appConfig.GroupNameTemplate,
+            GroupRoleKey:        &appConfig.GroupRoleKey,
+            GroupOnCallOpenIDs:  splitOpenIDs(appConfig.GroupOnCallOpenIDs),
+            GroupInviteMentions: &appConfig.GroupInviteMentions,
+            AlertChatID:         &appConfig.AlertChatID,
         },
+        Enabled: appConfig.Enabled,
     }
 
 	return response, nil
@@ -147,9 +182,10 @@ func (s *ConfigService) GetSignature(projectKey string) (string, error) {
 				Signature:  signature,
 				BotID:      "",
 				BotSecret:  "",
+				Enabled:    true,
 			}
 
-			if err := s.db.Create(&appConfig).Error; err != nil {
+			if err := s.createConfig(&appConfig); err != nil {
 				return "", err
 			}
 
@@ -176,6 +212,84 @@ func (s *ConfigService) GetSignature(projectKey string) (string, error) {
 	return appConfig.Signature, nil
 }
 
+// SetEnabled 启用或停用项目的机器人连接
+func (s *ConfigService) SetEnabled(projectKey string, enabled bool) error {
+	result := s.db.Model(&model.AppConfig{}).Where("project_key = ?", projectKey).
+		Updates(map[string]interface{}{
+			"enabled":    enabled,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		log.Printf("错误: 更新启用状态失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("config not found")
+	}
+	log.Printf("信息: 更新启用状态成功: project_key=%s, enabled=%v", projectKey, enabled)
+	return nil
+}
+
+// DeleteConfig 软删除配置，同时清空密钥并吊销签名
+func (s *ConfigService) DeleteConfig(projectKey string) error {
+	var appConfig model.AppConfig
+	if err := s.db.Where("project_key = ?", projectKey).First(&appConfig).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("config not found")
+		}
+		return err
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"bot_secret":             "",
+			"bot_verification_token": "",
+			"api_user_key":           "",
+			"signature":              gorm.Expr("NULL"),
+			"enabled":                false,
+			"updated_at":             time.Now(),
+		}
+		if err := tx.Model(&appConfig).Updates(updates).Error; err != nil {
+			log.Printf("错误: 清空配置密钥失败: %v", err)
+			return err
+		}
+		if err := tx.Delete(&appConfig).Error; err != nil {
+			log.Printf("错误: 删除配置失败: %v", err)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// 事务提交后再丢弃缓存的客户端，避免回滚时缓存已被清空或被并发请求按旧配置重建
+	s.clientRegistry.InvalidateLark(appConfig.BotID)
+	log.Printf("信息: 删除配置成功: project_key=%s", projectKey)
+	return nil
+}
+
+// createConfig 创建配置，若存在同项目已软删除的记录则复用并恢复该记录
+func (s *ConfigService) createConfig(appConfig *model.AppConfig) error {
+	var deleted model.AppConfig
+	err := s.db.Unscoped().Where("project_key = ? AND deleted_at IS NOT NULL", appConfig.ProjectKey).
+		Order("id DESC").First(&deleted).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.db.Create(appConfig).Error
+		}
+		return err
+	}
+
+	appConfig.ID = deleted.ID
+	appConfig.CreatedAt = deleted.CreatedAt
+	appConfig.DeletedAt = gorm.DeletedAt{}
+	if err := s.db.Unscoped().Save(appConfig).Error; err != nil {
+		return err
+	}
+	log.Printf("信息: 恢复已删除配置: project_key=%s", appConfig.ProjectKey)
+	return nil
+}
+
 // generateSignature 生成签名
 func (s *ConfigService) generateSignature(projectKey string) (string, error) {
 	uuidStr := uuid.New().String()
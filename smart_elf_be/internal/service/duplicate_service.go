@@ -0,0 +1,161 @@
+package service
+
+import (
+	"log"
+	"smart_elf_standalone/internal/model"
+	"smart_elf_standalone/pkg/config"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// 命中重复工单时的处理方式
+const (
+	DuplicateActionWatcher = "watcher"
+	DuplicateActionComment = "comment"
+)
+
+// 重复工单检测参数：单次比较的最大候选工单数、返回的最大匹配数
+const (
+	duplicateCandidateLimit = 500
+	duplicateMatchLimit     = 10
+)
+
+// DuplicateService 重复工单检测服务，维护各项目近期工单标题的本地索引
+type DuplicateService struct {
+	db  *gorm.DB
+	cfg config.DuplicateConfig
+}
+
+// NewDuplicateService 创建重复工单检测服务实例
+func NewDuplicateService(db *gorm.DB, cfg config.DuplicateConfig) *DuplicateService {
+	if cfg.Action == "" {
+		cfg.Action = DuplicateActionWatcher
+	}
+	return &DuplicateService{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// Enabled 是否开启重复工单检测
+func (s *DuplicateService) Enabled() bool {
+	return s.cfg.Enabled && s.cfg.Threshold > 0
+}
+
+// Action 命中重复时的处理方式
+func (s *DuplicateService) Action() string {
+	return s.cfg.Action
+}
+
+// FindDuplicates 在时间窗口内查找同一工作项类型中与标题相似度达到阈值的工单，按相似度从高到低排列，
+// 最多返回 duplicateMatchLimit 条
+func (s *DuplicateService) FindDuplicates(projectKey, workItemTypeKey, title string) ([]*model.TicketIndexEntry, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+	normalized := normalizeTitle(title)
+	if normalized == "" {
+		return nil, nil
+	}
+
+	var entries []*model.TicketIndexEntry
+	err := s.db.Where("project_key = ? AND work_item_type_key = ? AND created_at >= ?", projectKey, workItemTypeKey, s.windowStart()).
+		Order("created_at DESC").Limit(duplicateCandidateLimit).Find(&entries).Error
+	if err != nil {
+		log.Printf("错误: 查询工单索引失败: %v", err)
+		return nil, err
+	}
+
+	scores := make(map[*model.TicketIndexEntry]float64, len(entries))
+	matches := make([]*model.TicketIndexEntry, 0)
+	for _, entry := range entries {
+		score := titleSimilarity(normalized, entry.NormalizedTitle)
+		if score >= s.cfg.Threshold {
+			scores[entry] = score
+			matches = append(matches, entry)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return scores[matches[i]] > scores[matches[j]]
+	})
+	if len(matches) > duplicateMatchLimit {
+		matches = matches[:duplicateMatchLimit]
+	}
+	return matches, nil
+}
+
+// Forget 删除工单的索引记录，工单关闭或终止后不再作为合并目标
+func (s *DuplicateService) Forget(projectKey, workItemTypeKey string, workItemID int64) error {
+	err := s.db.Where("project_key = ? AND work_item_type_key = ? AND work_item_id = ?", projectKey, workItemTypeKey, workItemID).
+		Delete(&model.TicketIndexEntry{}).Error
+	if err != nil {
+		log.Printf("错误: 删除工单索引失败: %v", err)
+	}
+	return err
+}
+
+// Record 记录新建工单的标题，并清理窗口外的旧索引
+func (s *DuplicateService) Record(projectKey, workItemTypeKey string, workItemID int64, title string) error {
+	if !s.Enabled() {
+		return nil
+	}
+	entry := &model.TicketIndexEntry{
+		ProjectKey:      projectKey,
+		WorkItemTypeKey: workItemTypeKey,
+		WorkItemID:      workItemID,
+		Title:           title,
+		NormalizedTitle: normalizeTitle(title),
+	}
+	if err := s.db.Create(entry).Error; err != nil {
+		log.Printf("错误: 记录工单索引失败: %v", err)
+		return err
+	}
+	if err := s.db.Where("created_at < ?", s.windowStart()).Delete(&model.TicketIndexEntry{}).Error; err != nil {
+		log.Printf("警告: 清理工单索引失败: %v", err)
+	}
+	return nil
+}
+
+// windowStart 比较窗口的起始时间
+func (s *DuplicateService) windowStart() time.Time {
+	return time.Now().Add(-time.Duration(s.cfg.WindowMinutes) * time.Minute)
+}
+
+// normalizeTitle 统一大小写并去除空白与标点，便于比较
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// titleSimilarity 基于字符二元组的 Dice 系数计算相似度，兼顾中英文
+func titleSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < 2 || len(rb) < 2 {
+		return 0
+	}
+	grams := make(map[string]int, len(ra))
+	for i := 0; i < len(ra)-1; i++ {
+		grams[string(ra[i:i+2])]++
+	}
+	overlap := 0
+	for i := 0; i < len(rb)-1; i++ {
+		g := string(rb[i : i+2])
+		if grams[g] > 0 {
+			grams[g]--
+			overlap++
+		}
+	}
+	return 2 * float64(overlap) / float64(len(ra)-1+len(rb)-1)
+}
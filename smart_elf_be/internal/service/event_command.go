@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"smart_elf_standalone/internal/model"
+	"strconv"
+	"strings"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	projSDK "github.com/larksuite/project-oapi-sdk-golang"
+	"github.com/larksuite/project-oapi-sdk-golang/core"
+	"github.com/larksuite/project-oapi-sdk-golang/service/workitem"
+)
+
+// commandPrefix 聊天指令前缀
+const commandPrefix = "/"
+
+// /list 参数：最多展示的工单数、每页查询的工单数与最多查询的页数
+const (
+	commandListLimit    = 20
+	commandListPageSize = 50
+	commandListMaxPages = 10
+)
+
+// errNoPermission 调用者无权操作该工单
+var errNoPermission = errors.New("no permission")
+
+// commandContext 执行聊天指令所需的上下文
+type commandContext struct {
+	// callerConfig 为以调用者身份调用飞书项目的配置副本
+	callerConfig *model.AppConfig
+	larkCli      *lark.Client
+	meegoCli     *projSDK.Client
+	callerOpenID string
+}
+
+// chatCommand 机器人聊天指令
+type chatCommand struct {
+	name    string
+	usage   string
+	desc    string
+	handler func(ctx context.Context, cc *commandContext, args string) (string, error)
+}
+
+// commands 返回支持的聊天指令
+func (s *EventService) commands() []*chatCommand {
+	return []*chatCommand{
+		{name: "list", usage: "/list", desc: "查看我提交的未关闭工单 / List my open tickets", handler: s.commandList},
+		{name: "status", usage: "/status <id>", desc: "查看工单状态 / Show ticket status", handler: s.commandStatus},
+		{name: "close", usage: "/close <id>", desc: "关闭我提交的工单 / Close my ticket", handler: s.commandClose},
+		{name: "comment", usage: "/comment <id> <text>", desc: "评论工单 / Comment on a ticket", handler: s.commandComment},
+		{name: "help", usage: "/help", desc: "查看指令帮助 / Show this help"},
+	}
+}
+
+// parseCommand 解析聊天指令，仅识别支持的指令名，其他以"/"开头的消息返回 nil，按普通消息处理
+func (s *EventService) parseCommand(text string) (*chatCommand, string) {
+	if !strings.HasPrefix(text, commandPrefix) {
+		return nil, ""
+	}
+	name, args, _ := strings.Cut(strings.TrimPrefix(text, commandPrefix), " ")
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, c := range s.commands() {
+		if c.name == name {
+			return c, strings.TrimSpace(args)
+		}
+	}
+	return nil, ""
+}
+
+// handleCommand 执行聊天指令，结果回复给调用者
+func (s *EventService) handleCommand(ctx context.Context, larkCli *lark.Client, config *model.AppConfig,
+	cmd *chatCommand, args, callerOpenID, callerUnionID string) error {
+	if cmd.handler == nil {
+		return s.sendTextMessage(ctx, larkCli, callerOpenID, s.commandHelp())
+	}
+
+	reply, err := s.runCommand(ctx, cmd, larkCli, config, callerOpenID, callerUnionID, args)
+	if err != nil {
+		log.Printf("run command failed,command=%s,err=%s", cmd.name, err.Error())
+		reply = commandErrorText(err)
+	}
+	return s.sendTextMessage(ctx, larkCli, callerOpenID, reply)
+}
+
+// runCommand 以调用者的飞书项目身份执行指令，权限由飞书项目按该身份校验
+func (s *EventService) runCommand(ctx context.Context, cmd *chatCommand, larkCli *lark.Client, config *model.AppConfig,
+	callerOpenID, callerUnionID, args string) (string, error) {
+	meegoCli, err := s.GetFeishuProjectClient()
+	if err != nil {
+		log.Printf("错误: 获取飞书项目客户端失败: %v, project_key=%s", err, config.ProjectKey)
+		return "", err
+	}
+	callerUserKey, err := s.getMeegoUserKey(ctx, meegoCli, config, callerUnionID)
+	if err != nil {
+		return "未找到你的飞书项目账号，无法执行指令。\nNo Meego account is linked to you.", nil
+	}
+	callerConfig := *config
+	callerConfig.APIUserKey = callerUserKey
+	return cmd.handler(ctx, &commandContext{
+		callerConfig: &callerConfig,
+		larkCli:      larkCli,
+		meegoCli:     meegoCli,
+		callerOpenID: callerOpenID,
+	}, args)
+}
+
+// commandHelp 指令帮助文本
+func (s *EventService) commandHelp() string {
+	var b strings.Builder
+	b.WriteString("可用指令 / Available commands:")
+	for _, c := range s.commands() {
+		b.WriteString(fmt.Sprintf("\n%s  %s", c.usage, c.desc))
+	}
+	return b.String()
+}
+
+// commandErrorText 指令执行失败时的提示
+func commandErrorText(err error) string {
+	if errors.Is(err, errNoPermission) {
+		return "你没有权限执行该操作。\nYou don't have permission to do that."
+	}
+	var codeErr core.CodeError
+	if errors.As(err, &codeErr) {
+		return fmt.Sprintf("指令执行失败: %s\nCommand failed: %s", codeErr.ErrMsg, codeErr.ErrMsg)
+	}
+	return "指令执行失败，请稍后再试。\nCommand failed, please try again later."
+}
+
+// parseWorkItemID 解析指令参数中的工单ID
+func parseWorkItemID(arg string) (int64, bool) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+	return id, err == nil && id > 0
+}
+
+// commandList 列出调用者提交且未关闭的工单。关闭状态因工作流而异，无法在搜索条件中排除，
+// 逐页查询并跳过已关闭的工单，直到凑满 commandListLimit 条或查完（最多 commandListMaxPages 页）
+func (s *EventService) commandList(ctx context.Context, cc *commandContext, _ string) (string, error) {
+	var b strings.Builder
+	count := 0
+	for page := int64(1); page <= commandListMaxPages && count < commandListLimit; page++ {
+		items, more, err := s.searchReporterWorkItems(ctx, cc, page)
+		if err != nil {
+			return "", err
+		}
+		for _, wi := range items {
+			if isWorkItemClosed(wi) {
+				continue
+			}
+			count++
+			b.WriteString(fmt.Sprintf("\n#%d %s [%s]", wi.ID, wi.Name, workItemStateText(wi)))
+			if count == commandListLimit {
+				break
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	if count == 0 {
+		return "你没有未关闭的工单。\nYou have no open tickets.", nil
+	}
+	return fmt.Sprintf("你的未关闭工单 / Your open tickets (%d):%s", count, b.String()), nil
+}
+
+// searchReporterWorkItems 分页查询调用者提交的工单，返回本页工单及是否还有下一页
+func (s *EventService) searchReporterWorkItems(ctx context.Context, cc *commandContext, page int64) ([]*workitem.WorkItemInfo, bool, error) {
+	config := cc.callerConfig
+	req := workitem.NewSearchByParamsReqBuilder().ProjectKey(config.ProjectKey).WorkItemTypeKey(config.WorkItemTypeKey).
+		SearchGroup(&workitem.SearchGroup{
+			Conjunction: "AND",
+			SearchParams: []*workitem.SearchParam{{
+				ParamKey: config.CreatorFieldKey,
+				Value:    "###" + cc.callerOpenID,
+				Operator: "~",
+			}},
+		}).PageNum(page).PageSize(commandListPageSize).Build()
+	callCtx, cancel := s.callCtx(ctx, apiClassWorkItem)
+	defer cancel()
+	resp, err := cc.meegoCli.WorkItem.SearchByParams(callCtx, req, s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return nil, false, err
+	}
+	if !resp.Success() {
+		return nil, false, resp.CodeError
+	}
+	more := len(resp.Data) == commandListPageSize
+	if resp.Pagination != nil {
+		more = page*commandListPageSize < resp.Pagination.Total
+	}
+	return resp.Data, more, nil
+}
+
+// commandStatus 查看工单状态
+func (s *EventService) commandStatus(ctx context.Context, cc *commandContext, args string) (string, error) {
+	id, ok := parseWorkItemID(args)
+	if !ok {
+		return "用法 / Usage: /status <id>", nil
+	}
+	config := cc.callerConfig
+	wi, err := s.queryWorkItem(ctx, cc.meegoCli, config, config.WorkItemTypeKey, id, nil)
+	if err != nil {
+		return "", err
+	}
+	wiURL, err := s.buildWorkItemURL(ctx, cc.meegoCli, config, id)
+	if err != nil {
+		log.Printf("get project info failed,err=%s", err.Error())
+	}
+	return fmt.Sprintf("#%d %s\n状态 / Status: %s\n%s", wi.ID, wi.Name, workItemStateText(wi), wiURL), nil
+}
+
+// commandClose 关闭调用者提交的工单
+func (s *EventService) commandClose(ctx context.Context, cc *commandContext, args string) (string, error) {
+	id, ok := parseWorkItemID(args)
+	if !ok {
+		return "用法 / Usage: /close <id>", nil
+	}
+	config := cc.callerConfig
+	wi, err := s.queryWorkItem(ctx, cc.meegoCli, config, config.WorkItemTypeKey, id, []string{config.CreatorFieldKey})
+	if err != nil {
+		return "", err
+	}
+	if !isReporter(wi, config, cc.callerOpenID) {
+		return "", errNoPermission
+	}
+
+	req := workitem.NewAbortWorkItemReqBuilder().ProjectKey(config.ProjectKey).WorkItemTypeKey(config.WorkItemTypeKey).
+		WorkItemID(id).IsAborted(true).Reason("closed by reporter via bot command").Build()
+	callCtx, cancel := s.callCtx(ctx, apiClassWorkItem)
+	defer cancel()
+	resp, err := cc.meegoCli.WorkItem.AbortWorkItem(callCtx, req, s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success() {
+		return "", resp.CodeError
+	}
+	return fmt.Sprintf("工单 #%d 已关闭。\nTicket #%d has been closed.", id, id), nil
+}
+
+// commandComment 以调用者身份评论工单
+func (s *EventService) commandComment(ctx context.Context, cc *commandContext, args string) (string, error) {
+	idArg, text, _ := strings.Cut(args, " ")
+	id, ok := parseWorkItemID(idArg)
+	text = strings.TrimSpace(text)
+	if !ok || text == "" {
+		return "用法 / Usage: /comment <id> <text>", nil
+	}
+	config := cc.callerConfig
+	if err := s.createComment(ctx, cc.meegoCli, config, config.WorkItemTypeKey, id, text, config.APIUserKey); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("已评论工单 #%d。\nComment added to ticket #%d.", id, id), nil
+}
+
+// isReporter 判断调用者是否为工单提单人
+func isReporter(wi *workitem.WorkItemInfo, config *model.AppConfig, openID string) bool {
+	if wi.CreatedBy == config.APIUserKey {
+		return true
+	}
+	for _, f := range wi.Fields {
+		if f.FieldKey != config.CreatorFieldKey {
+			continue
+		}
+		if v, ok := f.FieldValue.(string); ok && strings.HasSuffix(v, "###"+openID) {
+			return true
+		}
+	}
+	return false
+}
+
+// workItemStateText 工单当前状态描述
+func workItemStateText(wi *workitem.WorkItemInfo) string {
+	if wi.WorkItemStatus != nil && wi.WorkItemStatus.StateKey != "" {
+		return wi.WorkItemStatus.StateKey
+	}
+	names := make([]string, 0, len(wi.CurrentNodes))
+	for _, n := range wi.CurrentNodes {
+		names = append(names, n.Name)
+	}
+	if len(names) > 0 {
+		return strings.Join(names, ",")
+	}
+	return wi.SubStage
+}
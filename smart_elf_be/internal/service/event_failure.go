@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"smart_elf_standalone/internal/model"
+	"strings"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	"github.com/larksuite/project-oapi-sdk-golang/core"
+)
+
+// failureReason 工单创建失败的中英文说明
+type failureReason struct {
+	CN string
+	EN string
+}
+
+// 飞书项目错误码，见飞书项目开放平台错误码文档 https://project.feishu.cn/b/helpcenter/1p8d7djs/5aueo3jr
+const (
+	meegoErrNoPermission = 10001
+	meegoErrRateLimited  = 10429
+	meegoErrInvalidParam = 20006
+	meegoErrNotFound     = 30005
+)
+
+// 工单创建失败的可读原因
+var (
+	requiredFieldReason = failureReason{"工单模板中存在未填写的必填字段，请联系空间管理员调整模板。", "A required field in the ticket template is missing. Please ask the space admin to adjust the template."}
+	permissionReason    = failureReason{"机器人没有该空间的操作权限，请联系空间管理员检查插件授权。", "The bot has no permission in this space. Please ask the space admin to check the plugin authorization."}
+	invalidParamReason  = failureReason{"工单字段配置有误，请联系空间管理员检查工单类型与模板配置。", "The ticket field configuration is invalid. Please ask the space admin to check the work item type and template."}
+	notFoundReason      = failureReason{"工单类型或模板不存在，请联系空间管理员检查配置。", "The work item type or template does not exist. Please ask the space admin to check the configuration."}
+	rateLimitReason     = failureReason{"飞书项目请求过于频繁，请稍后再试。", "Feishu Project is receiving too many requests. Please try again later."}
+	unavailableReason   = failureReason{"飞书项目服务暂时不可用，请稍后再试。", "Feishu Project is temporarily unavailable. Please try again later."}
+	unknownReason       = failureReason{"创建工单时发生未知错误，请联系空间管理员。", "An unexpected error occurred while creating the ticket. Please contact the space admin."}
+)
+
+// describeCreateFailure 将创建工作项的错误转换为可读原因。
+// 优先按飞书项目错误码（含内层错误码）判断，无法识别的错误码再按错误信息中的关键字匹配
+func describeCreateFailure(err error) failureReason {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return unavailableReason
+	}
+	var codeErr core.CodeError
+	if errors.As(err, &codeErr) {
+		for _, code := range []int{codeErr.ErrCode, codeErr.Err.Code} {
+			switch code {
+			case meegoErrNoPermission:
+				return permissionReason
+			case meegoErrRateLimited:
+				return rateLimitReason
+			case meegoErrInvalidParam:
+				return invalidParamReason
+			case meegoErrNotFound:
+				return notFoundReason
+			}
+		}
+	}
+
+	// 无法识别错误码时按错误信息中的关键字匹配
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "required") || strings.Contains(msg, "必填"):
+		return requiredFieldReason
+	case strings.Contains(msg, "permission") || strings.Contains(msg, "权限"):
+		return permissionReason
+	case strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many"):
+		return rateLimitReason
+	case codeErr.ErrCode == 0:
+		// 非飞书项目返回的错误，多为网络问题
+		return unavailableReason
+	}
+	return unknownReason
+}
+
+// ticketReferenceID 生成提供给支持人员排查的参考编号
+func ticketReferenceID(record *model.Ticket) string {
+	if record.ID > 0 {
+		return fmt.Sprintf("SE-%d", record.ID)
+	}
+	return record.SourceMessageID
+}
+
+// notifyCreateFailure 告知提单人工单创建失败，并按配置通知管理员群
+func (s *EventService) notifyCreateFailure(ctx context.Context, larkCli *lark.Client, config *model.AppConfig, record *model.Ticket, cause error) {
+	reason := describeCreateFailure(cause)
+	refID := ticketReferenceID(record)
+
+	text := fmt.Sprintf("❌工单创建失败: %s\n参考编号: %s\n❌Failed to create ticket: %s\nReference ID: %s",
+		reason.CN, refID, reason.EN, refID)
+	if err := s.sendTextMessage(ctx, larkCli, record.ReporterOpenID, text); err != nil {
+		log.Printf("send failure msg failed,err=%s", err.Error())
+	}
+
+	if config.AlertChatID == "" {
+		return
+	}
+	alert := fmt.Sprintf("⚠️工单创建失败 / Ticket creation failed\n参考编号 / Reference ID: %s\n空间 / Project: %s\n提单人 / Reporter: %s\n内容 / Content: %s\n原因 / Reason: %s\n错误 / Error: %s",
+		refID, config.ProjectKey, record.ReporterName, record.Title, reason.CN, cause.Error())
+	msgStr, _ := json.Marshal(model.TextContent{Text: alert})
+	if _, err := s.sendMessage(ctx, larkCli, "chat_id", config.AlertChatID, "text", string(msgStr)); err != nil {
+		log.Printf("send failure alert failed,err=%s", err.Error())
+	}
+}
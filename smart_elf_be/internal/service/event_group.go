@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"smart_elf_standalone/internal/model"
+	"strconv"
+	"strings"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+	projSDK "github.com/larksuite/project-oapi-sdk-golang"
+	"github.com/larksuite/project-oapi-sdk-golang/service/field"
+)
+
+// roleOwnersFieldKey 工作项角色负责人字段
+const roleOwnersFieldKey = "role_owners"
+
+// 工单群默认名称模板
+const (
+	defaultGroupNameTemplateCN = "[工单]{title}"
+	defaultGroupNameTemplateEN = "[Ticket]{title}"
+)
+
+// ticketGroup 创建工单群所需的工单信息
+type ticketGroup struct {
+	WorkItemID     int64
+	Title          string
+	ReporterName   string
+	ReporterOpenID string
+	// MentionOpenIDs 提单消息中@到的用户
+	MentionOpenIDs []string
+}
+
+// createTicketGroup 创建工单群：绑定工作项、邀请相关人员并发送置顶的工单摘要，返回群ID。
+// 绑定工作项失败时会解散已创建的群并返回错误
+func (s *EventService) createTicketGroup(ctx context.Context, larkCli *lark.Client, meegoCli *projSDK.Client,
+	config *model.AppConfig, ticket *ticketGroup) (string, error) {
+	titleCN := renderGroupName(config.GroupNameTemplate, defaultGroupNameTemplateCN, ticket)
+	titleEN := renderGroupName(config.GroupNameTemplate, defaultGroupNameTemplateEN, ticket)
+	reqCreateGroup := larkim.NewCreateChatReqBuilder().UserIdType("open_id").SetBotManager(true).
+		Body(
+			larkim.NewCreateChatReqBodyBuilder().
+				Name(titleCN).I18nNames(&larkim.I18nNames{
+				ZhCn: &titleCN,
+				EnUs: &titleEN,
+				JaJp: &titleEN,
+			}).
+				OwnerId(ticket.ReporterOpenID).
+				BotIdList([]string{config.BotID}).
+				Build()).
+		Build()
+	callCtx, cancel := s.callCtx(ctx, apiClassIM)
+	defer cancel()
+	respGroup, err := larkCli.Im.Chat.Create(callCtx, reqCreateGroup)
+	if err != nil {
+		return "", err
+	}
+	if !respGroup.Success() {
+		return "", fmt.Errorf("code=%d,msg=%s,requestID=%s", respGroup.Code, respGroup.Msg, respGroup.RequestId())
+	}
+	if respGroup.Data == nil || respGroup.Data.ChatId == nil {
+		return "", errors.New("chat id is nil")
+	}
+	chatID := *respGroup.Data.ChatId
+
+	// 绑定工作项失败时解散刚创建的群，避免留下无人维护的孤儿群，由重试重新拉群
+	upFields := []*field.FieldValuePair{
+		{FieldKey: "group_type", FieldValue: "bind"},
+		{FieldKey: "group_id", FieldValue: chatID},
+	}
+	if err := s.updateWorkItemFields(ctx, meegoCli, config, config.WorkItemTypeKey, ticket.WorkItemID, upFields); err != nil {
+		if errD := s.dissolveChat(ctx, larkCli, chatID); errD != nil {
+			log.Printf("dissolve orphan group failed,chat_id=%s,err=%s", chatID, errD.Error())
+		}
+		return "", fmt.Errorf("bind group to workitem failed: %w", err)
+	}
+
+	//以下步骤失败只打日志不影响后续流程
+	if err := s.recordTicketGroup(config, ticket.WorkItemID, chatID, titleCN, titleEN); err != nil {
+		log.Printf("record ticket group failed,err=%s", err.Error())
+	}
+	if err := s.ticketService.SetGroupChat(config.ProjectKey, ticket.WorkItemID, chatID); err != nil {
+		log.Printf("record ticket group chat failed,err=%s", err.Error())
+	}
+
+	s.inviteGroupMembers(ctx, larkCli, meegoCli, config, chatID, ticket)
+
+	wiURL, err := s.buildWorkItemURL(ctx, meegoCli, config, ticket.WorkItemID)
+	if err != nil {
+		log.Printf("get project info failed,err=%s", err.Error())
+	}
+	messageID, err := s.sendMessage(ctx, larkCli, "chat_id", chatID, "interactive", buildTicketSummaryCard(ticket, wiURL))
+	if err != nil {
+		log.Printf("send group summary failed,err=%s", err.Error())
+		return chatID, nil
+	}
+	if err := s.pinMessage(ctx, larkCli, messageID); err != nil {
+		log.Printf("pin group summary failed,err=%s", err.Error())
+	}
+	return chatID, nil
+}
+
+// inviteGroupMembers 邀请值班人员、消息中@的用户以及指定角色的负责人入群
+func (s *EventService) inviteGroupMembers(ctx context.Context, larkCli *lark.Client, meegoCli *projSDK.Client,
+	config *model.AppConfig, chatID string, ticket *ticketGroup) {
+	openIDs := make([]string, 0, 8)
+	openIDs = append(openIDs, splitOpenIDs(config.GroupOnCallOpenIDs)...)
+	if config.GroupInviteMentions {
+		openIDs = append(openIDs, ticket.MentionOpenIDs...)
+	}
+	if err := s.addChatMembers(ctx, larkCli, chatID, "open_id", dedupeIDs(openIDs, ticket.ReporterOpenID)); err != nil {
+		log.Printf("invite group members failed,err=%s", err.Error())
+	}
+
+	if config.GroupRoleKey == "" {
+		return
+	}
+	unionIDs, err := s.getRoleOwnerUnionIDs(ctx, meegoCli, config, ticket.WorkItemID)
+	if err != nil {
+		log.Printf("get role owners failed,err=%s", err.Error())
+		return
+	}
+	if err := s.addChatMembers(ctx, larkCli, chatID, "union_id", dedupeIDs(unionIDs, "")); err != nil {
+		log.Printf("invite role owners failed,err=%s", err.Error())
+	}
+}
+
+// getRoleOwnerUnionIDs 获取工作项指定角色负责人的飞书 union_id
+func (s *EventService) getRoleOwnerUnionIDs(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, workItemID int64) ([]string, error) {
+	info, err := s.queryWorkItem(ctx, meegoCli, config, config.WorkItemTypeKey, workItemID, []string{roleOwnersFieldKey})
+	if err != nil {
+		return nil, err
+	}
+	userKeys := make([]string, 0, 4)
+	for _, f := range info.Fields {
+		if f.FieldKey != roleOwnersFieldKey {
+			continue
+		}
+		roles, _ := f.FieldValue.([]interface{})
+		for _, r := range roles {
+			role, ok := r.(map[string]interface{})
+			if !ok || role["role"] != config.GroupRoleKey {
+				continue
+			}
+			owners, _ := role["owners"].([]interface{})
+			for _, o := range owners {
+				if key, ok := o.(string); ok {
+					userKeys = append(userKeys, key)
+				}
+			}
+		}
+	}
+	if len(userKeys) == 0 {
+		return nil, nil
+	}
+	users, err := s.getMeegoUsers(ctx, meegoCli, config, userKeys)
+	if err != nil {
+		return nil, err
+	}
+	unionIDs := make([]string, 0, len(users))
+	for _, u := range users {
+		if u.OutID != "" {
+			unionIDs = append(unionIDs, u.OutID)
+		}
+	}
+	return unionIDs, nil
+}
+
+// addChatMembers 邀请用户入群，不可用的ID会被跳过
+func (s *EventService) addChatMembers(ctx context.Context, larkCli *lark.Client, chatID, idType string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	callCtx, cancel := s.callCtx(ctx, apiClassIM)
+	defer cancel()
+	resp, err := larkCli.Im.ChatMembers.Create(callCtx, larkim.NewCreateChatMembersReqBuilder().
+		ChatId(chatID).MemberIdType(idType).SucceedType(1).
+		Body(larkim.NewCreateChatMembersReqBodyBuilder().IdList(ids).Build()).
+		Build())
+	if err != nil {
+		return err
+	}
+	if !resp.Success() {
+		return fmt.Errorf("code=%d,msg=%s,requestID=%s", resp.Code, resp.Msg, resp.RequestId())
+	}
+	if resp.Data != nil && len(resp.Data.InvalidIdList) > 0 {
+		log.Printf("invalid group members skipped,ids=%v", resp.Data.InvalidIdList)
+	}
+	return nil
+}
+
+// pinMessage 置顶群消息
+func (s *EventService) pinMessage(ctx context.Context, larkCli *lark.Client, messageID string) error {
+	callCtx, cancel := s.callCtx(ctx, apiClassIM)
+	defer cancel()
+	resp, err := larkCli.Im.Pin.Create(callCtx, larkim.NewCreatePinReqBuilder().
+		Body(larkim.NewCreatePinReqBodyBuilder().MessageId(messageID).Build()).
+		Build())
+	if err != nil {
+		return err
+	}
+	if !resp.Success() {
+		return fmt.Errorf("code=%d,msg=%s,requestID=%s", resp.Code, resp.Msg, resp.RequestId())
+	}
+	return nil
+}
+
+// renderGroupName 按模板生成群名称，支持 {title}、{id}、{reporter} 占位符
+func renderGroupName(template, defaultTemplate string, ticket *ticketGroup) string {
+	if template == "" {
+		template = defaultTemplate
+	}
+	return strings.NewReplacer(
+		"{title}", ticket.Title,
+		"{id}", strconv.FormatInt(ticket.WorkItemID, 10),
+		"{reporter}", ticket.ReporterName,
+	).Replace(template)
+}
+
+// buildTicketSummaryCard 构建工单群中的工单摘要卡片
+func buildTicketSummaryCard(ticket *ticketGroup, wiURL string) string {
+	elements := []map[string]interface{}{
+		{
+			"tag": "div",
+			"text": map[string]interface{}{
+				"tag": "lark_md",
+				"content": fmt.Sprintf("**工单内容 / Content:** %s\n**提单人 / Reporter:** %s",
+					ticket.Title, ticket.ReporterName),
+			},
+		},
+	}
+	if wiURL != "" {
+		elements = append(elements, map[string]interface{}{
+			"tag": "action",
+			"actions": []map[string]interface{}{{
+				"tag":  "button",
+				"type": "primary",
+				"url":  wiURL,
+				"text": map[string]interface{}{"tag": "plain_text", "content": "查看详情 / View Detail"},
+			}},
+		})
+	}
+	card := map[string]interface{}{
+		"config": map[string]interface{}{"wide_screen_mode": true},
+		"header": map[string]interface{}{
+			"template": "blue",
+			"title": map[string]interface{}{
+				"tag":     "plain_text",
+				"content": fmt.Sprintf("🆕工单 / Ticket #%d", ticket.WorkItemID),
+			},
+		},
+		"elements": elements,
+	}
+	cardStr, _ := json.Marshal(card)
+	return string(cardStr)
+}
+
+// splitOpenIDs 解析逗号分隔的 open_id 列表
+func splitOpenIDs(ids string) []string {
+	result := make([]string, 0, 4)
+	for _, id := range strings.Split(ids, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+// dedupeIDs 去重并剔除指定ID
+func dedupeIDs(ids []string, exclude string) []string {
+	seen := make(map[string]bool, len(ids))
+	result := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || id == exclude || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"smart_elf_standalone/internal/model"
+	"time"
+
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
+	"github.com/larksuite/project-oapi-sdk-golang/service/workitem"
+)
+
+// 工单关闭后的群处理方式
+const (
+	GroupCloseActionDissolve = "dissolve"
+	GroupCloseActionLeave    = "leave"
+)
+
+// workItemSubStageAborted 工作项被终止后的阶段
+const workItemSubStageAborted = "aborted"
+
+// 工单关闭后群名称的默认前缀
+const (
+	defaultResolvedPrefixCN = "[已解决]"
+	resolvedPrefixEN        = "[Resolved]"
+)
+
+// recordTicketGroup 记录工单群与工作项的关联
+func (s *EventService) recordTicketGroup(config *model.AppConfig, workItemID int64, chatID, nameCN, nameEN string) error {
+	group := &model.TicketGroup{
+		ProjectKey:      config.ProjectKey,
+		WorkItemTypeKey: config.WorkItemTypeKey,
+		WorkItemID:      workItemID,
+		ChatID:          chatID,
+		NameCN:          nameCN,
+		NameEN:          nameEN,
+		Status:          model.TicketGroupStatusActive,
+	}
+	return s.db.Create(group).Error
+}
+
+// groupCloseCheckInterval 未开启定时对账时检查已解决工单群是否到期归档的间隔
+const groupCloseCheckInterval = time.Minute
+
+// StartGroupReconciler 按配置的间隔定时对账工单群，服务停机后退出。
+// 未开启定时对账但配置了归档延迟时，仍定时归档到期的已解决工单群
+func (s *EventService) StartGroupReconciler() {
+	interval := time.Duration(s.groupCfg.ReconcileIntervalMinutes) * time.Minute
+	run := s.ReconcileGroups
+	if interval <= 0 {
+		if s.groupCfg.CloseDelayMinutes <= 0 {
+			return
+		}
+		interval = groupCloseCheckInterval
+		run = s.closeExpiredGroups
+	}
+	s.goTracked(func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.lifecycle.stopCh:
+				return
+			case <-ticker.C:
+				run(ctx)
+			}
+		}
+	})
+	log.Printf("信息: 工单群定时对账已启动: interval=%s", interval)
+}
+
+// ReconcileGroups 按空间与工作项类型分批查询未关闭工单群对应的工作项，处理已关闭工单对应的群，
+// 并归档到期的已解决工单群
+func (s *EventService) ReconcileGroups(ctx context.Context) {
+	var groups []*model.TicketGroup
+	if err := s.db.Where("status = ?", model.TicketGroupStatusActive).Find(&groups).Error; err != nil {
+		log.Printf("错误: 查询工单群失败: %v", err)
+		return
+	}
+	type groupKey struct {
+		projectKey string
+		typeKey    string
+	}
+	byKey := make(map[groupKey][]*model.TicketGroup)
+	for _, group := range groups {
+		key := groupKey{group.ProjectKey, group.WorkItemTypeKey}
+		byKey[key] = append(byKey[key], group)
+	}
+	for key, list := range byKey {
+		if s.stopping() {
+			return
+		}
+		if err := s.resolveClosedGroups(ctx, key.projectKey, key.typeKey, list); err != nil {
+			log.Printf("错误: 工单群对账失败: %v, project_key=%s, work_item_type_key=%s", err, key.projectKey, key.typeKey)
+		}
+	}
+	s.closeExpiredGroups(ctx)
+}
+
+// resolveClosedGroups 分批查询同一空间、同一工作项类型的工单群对应的工作项，为已关闭的工单发送总结并改名
+func (s *EventService) resolveClosedGroups(ctx context.Context, projectKey, workItemTypeKey string, groups []*model.TicketGroup) error {
+	config, err := s.configService.GetConfigByProjectKey(projectKey)
+	if err != nil {
+		return err
+	}
+	larkCli, err := s.getLarkSDKCli(config)
+	if err != nil {
+		return err
+	}
+	meegoCli, err := s.GetFeishuProjectClient()
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(groups); start += ticketSyncBatchSize {
+		if s.stopping() {
+			return nil
+		}
+		end := start + ticketSyncBatchSize
+		if end > len(groups) {
+			end = len(groups)
+		}
+		batch := groups[start:end]
+		ids := make([]int64, 0, len(batch))
+		for _, group := range batch {
+			ids = append(ids, group.WorkItemID)
+		}
+		items, err := s.queryWorkItems(ctx, meegoCli, config, workItemTypeKey, ids, nil)
+		if err != nil {
+			log.Printf("错误: 查询工单群对应的工作项失败: %v, project_key=%s, work_item_type_key=%s", err, projectKey, workItemTypeKey)
+			continue
+		}
+		infos := make(map[int64]*workitem.WorkItemInfo, len(items))
+		for _, wi := range items {
+			infos[wi.ID] = wi
+		}
+		for _, group := range batch {
+			wi, ok := infos[group.WorkItemID]
+			if !ok || !isWorkItemClosed(wi) {
+				continue
+			}
+			if err := s.resolveGroup(ctx, larkCli, group, wi); err != nil {
+				log.Printf("错误: 工单群对账失败: %v, chat_id=%s, work_item_id=%d", err, group.ChatID, group.WorkItemID)
+			}
+		}
+	}
+	return nil
+}
+
+// closeExpiredGroups 归档已解决且超过延迟时间的工单群
+func (s *EventService) closeExpiredGroups(ctx context.Context) {
+	deadline := time.Now().Add(-time.Duration(s.groupCfg.CloseDelayMinutes) * time.Minute)
+	var groups []*model.TicketGroup
+	err := s.db.Where("status = ? AND resolved_at <= ?", model.TicketGroupStatusResolved, deadline).Find(&groups).Error
+	if err != nil {
+		log.Printf("错误: 查询待归档工单群失败: %v", err)
+		return
+	}
+	for _, group := range groups {
+		if s.stopping() {
+			return
+		}
+		if err := s.reconcileGroup(ctx, group); err != nil {
+			log.Printf("错误: 工单群归档失败: %v, chat_id=%s, work_item_id=%d", err, group.ChatID, group.WorkItemID)
+		}
+	}
+}
+
+// HandleWorkItemEvent 处理飞书项目工作项状态变更事件
+func (s *EventService) HandleWorkItemEvent(ctx context.Context, signature string, req *model.MeegoEventRequest) error {
+	if req == nil || req.Payload == nil || req.Payload.ID == 0 {
+		return errors.New("invalid meego event request")
+	}
+	config, err := s.configService.GetConfigBySignature(signature)
+	if err != nil {
+		log.Printf("错误: 验证签名失败: %v, signature=%s", err, signature)
+		return err
+	}
+	if req.Payload.ProjectKey != "" && req.Payload.ProjectKey != config.ProjectKey {
+		return fmt.Errorf("project_key mismatch: %s", req.Payload.ProjectKey)
+	}
+
+	var groups []*model.TicketGroup
+	err = s.db.Where("project_key = ? AND work_item_id = ? AND status IN ?", config.ProjectKey, req.Payload.ID,
+		[]string{model.TicketGroupStatusActive, model.TicketGroupStatusResolved}).Find(&groups).Error
+	if err != nil {
+		return err
+	}
+	for _, group := range groups {
+		if err := s.reconcileGroup(ctx, group); err != nil {
+			log.Printf("错误: 工单群对账失败: %v, chat_id=%s, work_item_id=%d", err, group.ChatID, group.WorkItemID)
+		}
+	}
+	return nil
+}
+
+// reconcileGroup 工单关闭时发送总结并改名，超过延迟时间后解散群或机器人退群
+func (s *EventService) reconcileGroup(ctx context.Context, group *model.TicketGroup) error {
+	config, err := s.configService.GetConfigByProjectKey(group.ProjectKey)
+	if err != nil {
+		return err
+	}
+	larkCli, err := s.getLarkSDKCli(config)
+	if err != nil {
+		return err
+	}
+
+	if group.Status == model.TicketGroupStatusActive {
+		meegoCli, err := s.GetFeishuProjectClient()
+		if err != nil {
+			log.Printf("错误: 获取飞书项目客户端失败: %v, project_key=%s", err, group.ProjectKey)
+			return err
+		}
+		wi, err := s.queryWorkItem(ctx, meegoCli, config, group.WorkItemTypeKey, group.WorkItemID, nil)
+		if err != nil {
+			return err
+		}
+		if !isWorkItemClosed(wi) {
+			return nil
+		}
+		if err := s.resolveGroup(ctx, larkCli, group, wi); err != nil {
+			return err
+		}
+	}
+
+	if group.Status == model.TicketGroupStatusResolved && group.ResolvedAt != nil &&
+		time.Since(*group.ResolvedAt) >= time.Duration(s.groupCfg.CloseDelayMinutes)*time.Minute {
+		return s.closeGroup(ctx, larkCli, config, group)
+	}
+	return nil
+}
+
+// resolveGroup 发送工单关闭总结并为群名称加上已解决前缀
+func (s *EventService) resolveGroup(ctx context.Context, larkCli *lark.Client, group *model.TicketGroup, wi *workitem.WorkItemInfo) error {
+	summary := fmt.Sprintf("✅工单 #%d 已关闭，状态: %s\nTicket #%d has been closed, status: %s", wi.ID, workItemStateText(wi), wi.ID, workItemStateText(wi))
+	if s.groupCfg.CloseDelayMinutes > 0 {
+		summary += fmt.Sprintf("\n本群将在 %d 分钟后归档。\nThis group will be archived in %d minutes.", s.groupCfg.CloseDelayMinutes, s.groupCfg.CloseDelayMinutes)
+	}
+	msgStr, _ := json.Marshal(model.TextContent{Text: summary})
+	if _, err := s.sendMessage(ctx, larkCli, "chat_id", group.ChatID, "text", string(msgStr)); err != nil {
+		log.Printf("send close summary failed,err=%s", err.Error())
+	}
+
+	prefixCN := s.groupCfg.ResolvedPrefix
+	if prefixCN == "" {
+		prefixCN = defaultResolvedPrefixCN
+	}
+	nameCN := prefixCN + group.NameCN
+	nameEN := resolvedPrefixEN + group.NameEN
+	callCtx, cancel := s.callCtx(ctx, apiClassIM)
+	defer cancel()
+	resp, err := larkCli.Im.Chat.Update(callCtx, larkim.NewUpdateChatReqBuilder().ChatId(group.ChatID).
+		Body(larkim.NewUpdateChatReqBodyBuilder().Name(nameCN).I18nNames(&larkim.I18nNames{
+			ZhCn: &nameCN,
+			EnUs: &nameEN,
+			JaJp: &nameEN,
+		}).Build()).
+		Build())
+	if err != nil {
+		log.Printf("rename group failed,err=%s", err.Error())
+	} else if !resp.Success() {
+		log.Printf("rename group failed,code=%d,msg=%s,requestID=%s", resp.Code, resp.Msg, resp.RequestId())
+	}
+
+	now := time.Now()
+	group.Status = model.TicketGroupStatusResolved
+	group.ResolvedAt = &now
+	return s.db.Model(group).Updates(map[string]interface{}{
+		"status":      group.Status,
+		"resolved_at": now,
+	}).Error
+}
+
+// closeGroup 按配置解散工单群或让机器人退群
+func (s *EventService) closeGroup(ctx context.Context, larkCli *lark.Client, config *model.AppConfig, group *model.TicketGroup) error {
+	if s.groupCfg.CloseAction == GroupCloseActionLeave {
+		callCtx, cancel := s.callCtx(ctx, apiClassIM)
+		defer cancel()
+		resp, err := larkCli.Im.ChatMembers.Delete(callCtx, larkim.NewDeleteChatMembersReqBuilder().
+			ChatId(group.ChatID).MemberIdType("app_id").
+			Body(larkim.NewDeleteChatMembersReqBodyBuilder().IdList([]string{config.BotID}).Build()).
+			Build())
+		if err != nil {
+			return err
+		}
+		if !resp.Success() {
+			return fmt.Errorf("code=%d,msg=%s,requestID=%s", resp.Code, resp.Msg, resp.RequestId())
+		}
+	} else {
+		if err := s.dissolveChat(ctx, larkCli, group.ChatID); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	group.Status = model.TicketGroupStatusClosed
+	group.ClosedAt = &now
+	log.Printf("信息: 工单群已归档: chat_id=%s, work_item_id=%d", group.ChatID, group.WorkItemID)
+	return s.db.Model(group).Updates(map[string]interface{}{
+		"status":    group.Status,
+		"closed_at": now,
+	}).Error
+}
+
+// dissolveChat 解散群聊
+func (s *EventService) dissolveChat(ctx context.Context, larkCli *lark.Client, chatID string) error {
+	callCtx, cancel := s.callCtx(ctx, apiClassIM)
+	defer cancel()
+	resp, err := larkCli.Im.Chat.Delete(callCtx, larkim.NewDeleteChatReqBuilder().ChatId(chatID).Build())
+	if err != nil {
+		return err
+	}
+	if !resp.Success() {
+		return fmt.Errorf("code=%d,msg=%s,requestID=%s", resp.Code, resp.Msg, resp.RequestId())
+	}
+	return nil
+}
+
+// isWorkItemClosed 工作项是否已关闭，已删除、进入终态或被终止（如 /close 指令）均视为关闭
+func isWorkItemClosed(wi *workitem.WorkItemInfo) bool {
+	if wi.DeletedAt > 0 || isWorkItemAborted(wi) {
+		return true
+	}
+	return wi.WorkItemStatus != nil && wi.WorkItemStatus.IsArchivedState
+}
+
+// isWorkItemAborted 工作项是否已终止
+func isWorkItemAborted(wi *workitem.WorkItemInfo) bool {
+	return wi.SubStage == workItemSubStageAborted
+}
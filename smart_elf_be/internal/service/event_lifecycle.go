@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// shutdownReleaseTimeout 排空超时取消任务后，等待任务释放已领取步骤的时间上限
+const shutdownReleaseTimeout = 5 * time.Second
+
+// eventLifecycle 跟踪事件服务派生的后台协程，支持停机时排空
+type eventLifecycle struct {
+	// ctx 在排空超时后取消，用于中断仍在执行的外部调用
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopCh 在开始停机时关闭，通知定时任务退出
+	stopCh  chan struct{}
+	mu      sync.RWMutex
+	closing bool
+	wg      sync.WaitGroup
+}
+
+func newEventLifecycle() *eventLifecycle {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &eventLifecycle{
+		ctx:    ctx,
+		cancel: cancel,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// goTracked 在服务生命周期内启动协程，服务停机后返回 false 且不启动
+func (s *EventService) goTracked(fn func(ctx context.Context)) bool {
+	s.lifecycle.mu.RLock()
+	defer s.lifecycle.mu.RUnlock()
+	if s.lifecycle.closing {
+		return false
+	}
+	s.lifecycle.wg.Add(1)
+	go func() {
+		defer s.lifecycle.wg.Done()
+		fn(s.lifecycle.ctx)
+	}()
+	return true
+}
+
+// stopping 服务是否已开始停机
+func (s *EventService) stopping() bool {
+	select {
+	case <-s.lifecycle.stopCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// Shutdown 停止接收新的后台任务并等待进行中的任务完成。
+// ctx 到期后取消仍在执行的任务，并再等待至多 shutdownReleaseTimeout 让任务释放已领取的步骤，
+// 未完成的步骤保留在数据库中，下次启动时恢复执行
+func (s *EventService) Shutdown(ctx context.Context) error {
+	s.lifecycle.mu.Lock()
+	if !s.lifecycle.closing {
+		s.lifecycle.closing = true
+		close(s.lifecycle.stopCh)
+	}
+	s.lifecycle.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.lifecycle.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.lifecycle.cancel()
+		log.Printf("信息: 事件后台任务已全部完成")
+		return nil
+	case <-ctx.Done():
+		s.lifecycle.cancel()
+		log.Printf("警告: 等待事件后台任务超时，未完成的步骤将在下次启动时恢复")
+	}
+
+	select {
+	case <-done:
+	case <-time.After(shutdownReleaseTimeout):
+		log.Printf("错误: 等待事件后台任务释放步骤超时")
+	}
+	return ctx.Err()
+}
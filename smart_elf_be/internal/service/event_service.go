@@ -15,26 +15,49 @@ import (
 	larkcontact "github.com/larksuite/oapi-sdk-go/v3/service/contact/v3"
 	larkim "github.com/larksuite/oapi-sdk-go/v3/service/im/v1"
 	projSDK "github.com/larksuite/project-oapi-sdk-golang"
-	"github.com/larksuite/project-oapi-sdk-golang/core"
 	"github.com/larksuite/project-oapi-sdk-golang/service/field"
-	"github.com/larksuite/project-oapi-sdk-golang/service/project"
 	"github.com/larksuite/project-oapi-sdk-golang/service/workitem"
 	"gorm.io/gorm"
 )
 
+// botPausedText 连接停用时回复给提单人的提示
+const botPausedText = "机器人已暂停服务，暂不受理新工单，请联系空间管理员。\nThe bot is paused and is not accepting new tickets. Please contact your space admin."
+
+// rateLimitedText 提单或发送指令过于频繁时回复给发送者的提示
+const rateLimitedText = "操作过于频繁，请稍后再试。\nYou are sending requests too quickly. Please try again later."
+
 // EventService 事件服务
 type EventService struct {
-	db            *gorm.DB
-	configService *ConfigService
-	feishuCfg     config.FeishuConfig
+	db               *gorm.DB
+	configService    *ConfigService
+	rateLimitService *RateLimitService
+	duplicateService *DuplicateService
+	ticketService    *TicketService
+	stepService      *TicketStepService
+	clientRegistry   *ClientRegistry
+	metadataService  *MetadataService
+	userTokenService *UserTokenService
+	groupCfg         config.GroupConfig
+	lifecycle        *eventLifecycle
 }
 
 // NewEventService 创建事件服务实例
-func NewEventService(db *gorm.DB, configService *ConfigService, feishuCfg config.FeishuConfig) *EventService {
+func NewEventService(db *gorm.DB, configService *ConfigService, rateLimitService *RateLimitService,
+	duplicateService *DuplicateService, ticketService *TicketService, stepService *TicketStepService,
+	clientRegistry *ClientRegistry, metadataService *MetadataService, userTokenService *UserTokenService,
+	groupCfg config.GroupConfig) *EventService {
 	return &EventService{
-		db:            db,
-		configService: configService,
-		feishuCfg:     feishuCfg,
+		db:               db,
+		configService:    configService,
+		rateLimitService: rateLimitService,
+		duplicateService: duplicateService,
+		ticketService:    ticketService,
+		stepService:      stepService,
+		clientRegistry:   clientRegistry,
+		metadataService:  metadataService,
+		userTokenService: userTokenService,
+		groupCfg:         groupCfg,
+		lifecycle:        newEventLifecycle(),
 	}
 }
 
@@ -54,13 +77,11 @@ func (s *EventService) getLarkSDKCli(config *model.AppConfig) (*lark.Client, err
 		return nil, errors.New("invalid bot configuration")
 	}
 
-	// 创建飞书SDK客户端
-	client := lark.NewClient(config.BotID, config.BotSecret, lark.WithOpenBaseUrl(s.feishuCfg.IMOpenAPIHost))
-	return client, nil
+	// 复用按机器人缓存的飞书SDK客户端
+	return s.clientRegistry.LarkClient(config.BotID, config.BotSecret)
 }
 
-func (s *EventService) HandleMessageEvent(req *model.LarkCallbackRequest) (err error) {
-	ctx := context.Background()
+func (s *EventService) HandleMessageEvent(ctx context.Context, req *model.LarkCallbackRequest) (err error) {
 	if req == nil || req.Event == nil || req.Event.Message == nil {
 		return errors.New("invalid event request")
 	}
@@ -76,8 +97,10 @@ func (s *EventService) HandleMessageEvent(req *model.LarkCallbackRequest) (err e
 	// 获取发送者信息
 	sender := req.Event.Sender
 	senderID := ""
+	senderUnionID := ""
 	if sender != nil && sender.SenderID != nil {
 		senderID = sender.SenderID.OpenID
+		senderUnionID = sender.SenderID.UnionID
 	}
 
 	// 忽略机器人自己发送的消息
@@ -96,205 +119,501 @@ func (s *EventService) HandleMessageEvent(req *model.LarkCallbackRequest) (err e
 		return
 	}
 	reporterOpenID := senderID
-	userResp, err := larkCli.Contact.User.Get(ctx, larkcontact.NewGetUserReqBuilder().
-		UserIdType("open_id").UserId(reporterOpenID).Build())
+
+	// 连接已停用时只回复提示，不创建工单
+	if !config.Enabled {
+		log.Printf("信息: 机器人连接已停用: project_key=%s", config.ProjectKey)
+		if errP := s.sendTextMessage(ctx, larkCli, reporterOpenID, botPausedText); errP != nil {
+			log.Printf("send paused msg failed,err=%s", errP.Error())
+		}
+		return nil
+	}
+
+	contentText := extractContentText(content.Text)
+
+	// 按提单人、群聊与项目维度限流，聊天指令与建单共用令牌，限流检查失败时不阻塞处理
+	allowed, _, errL := s.rateLimitService.Allow(config.ProjectKey, message.ChatID, reporterOpenID)
+	if errL != nil {
+		log.Printf("check rate limit failed,err=%s", errL.Error())
+	} else if !allowed {
+		if errP := s.sendTextMessage(ctx, larkCli, reporterOpenID, rateLimitedText); errP != nil {
+			log.Printf("send rate limited msg failed,err=%s", errP.Error())
+		}
+		return nil
+	}
+
+	// 以"/"加支持的指令名开头的消息作为聊天指令处理，不创建工单
+	if cmd, args := s.parseCommand(contentText); cmd != nil {
+		if errC := s.handleCommand(ctx, larkCli, config, cmd, args, reporterOpenID, senderUnionID); errC != nil {
+			log.Printf("handle command failed,err=%s", errC.Error())
+		}
+		return nil
+	}
+
+	reporterDisplayName, err := s.getReporterName(ctx, larkCli, reporterOpenID)
 	if err != nil {
 		log.Printf("get lark user failed,err=%s", err.Error())
 		return
 	}
-	if !userResp.Success() {
-		log.Printf("get lark user failed,code=%s,msg=%s,requestID=%s", userResp.Code, userResp.Msg, userResp.RequestId())
-		return
+
+	// 记录工单台账
+	record := &model.Ticket{
+		ProjectKey:      config.ProjectKey,
+		WorkItemTypeKey: config.WorkItemTypeKey,
+		Title:           contentText,
+		SourceMessageID: message.MessageID,
+		SourceChatID:    message.ChatID,
+		SourceChatType:  message.ChatType,
+		SourceThreadID:  messageThreadID(message),
+		ReporterOpenID:  reporterOpenID,
+		ReporterUnionID: senderUnionID,
+		ReporterName:    reporterDisplayName,
 	}
-	reporterDisplayName := userResp.Data.User.Name
-	if reporterDisplayName == nil {
-		log.Printf("reporterDisplayName is nil")
-		return
+	if errT := s.ticketService.CreatePending(record); errT != nil {
+		log.Printf("record ticket failed,err=%s", errT.Error())
 	}
 
-	type TextContent struct {
-		Text string `json:"text"`
+	meegoCli, errC := s.GetFeishuProjectClient()
+	if errC != nil {
+		log.Printf("错误: 获取飞书项目客户端失败: %v, project_key=%s", errC, config.ProjectKey)
+		if errT := s.ticketService.MarkFailed(record.ID, errC); errT != nil {
+			log.Printf("update ticket failed,err=%s", errT.Error())
+		}
+		s.notifyCreateFailure(ctx, larkCli, config, record, errC)
+		return
 	}
+	// 建单、加关注人与评论属于提单人的操作，每个事件只解析一次提单人身份
+	reporterCfg, reporterUserKey := s.reporterConfig(ctx, meegoCli, config, senderUnionID)
 
-	meegoCli, _ := s.GetFeishuProjectClient()
-
-	reg := regexp.MustCompile(`@_user_[0-9]+`)
-	contentText := reg.ReplaceAllString(content.Text, "")
-	contentText = strings.TrimSpace(contentText)
+	// 命中近期相似工单时合并到已有工单，不再新建
+	dup, errD := s.findOpenDuplicate(ctx, meegoCli, config, contentText)
+	if errD != nil {
+		log.Printf("find duplicate ticket failed,err=%s", errD.Error())
+	} else if dup != nil {
+		errM := s.mergeIntoTicket(ctx, meegoCli, larkCli, config, reporterCfg, dup, reporterDisplayName, reporterOpenID, reporterUserKey, contentText)
+		if errM == nil {
+			if errT := s.ticketService.MarkMerged(record.ID, dup.WorkItemID); errT != nil {
+				log.Printf("update ticket failed,err=%s", errT.Error())
+			}
+			return nil
+		}
+		log.Printf("merge into duplicate ticket failed,err=%s", errM.Error())
+	}
 
 	//创建工单工作项
-	userKey := config.APIUserKey
-	fields := make([]*field.FieldValuePair, 0, 1)
-	fields = append(fields, &field.FieldValuePair{
-		FieldValue: fmt.Sprintf("%s###%s", *reporterDisplayName, reporterOpenID),
-		FieldKey:   config.CreatorFieldKey})
-	wiReq := workitem.NewCreateWorkItemReqBuilder().WorkItemTypeKey(config.WorkItemTypeKey).
-		ProjectKey(config.ProjectKey).Name(contentText).FieldValuePairs(fields).TemplateID(config.WorkItemTemplateID).Build()
-	wiResp, err := meegoCli.WorkItem.CreateWorkItem(ctx, wiReq, core.WithUserKey(userKey))
-
+	payload := s.buildWorkItemPayload(config, reporterDisplayName, reporterOpenID, contentText)
+	wiID, err := s.createWorkItem(ctx, meegoCli, reporterCfg, payload)
 	if err != nil {
-		log.Printf("create workitem failed,err=%s, logid=%s", err.Error(), wiResp.Header.Get("x-tt-logid"))
+		log.Printf("create workitem failed,err=%s", err.Error())
+		if errT := s.ticketService.MarkFailed(record.ID, err); errT != nil {
+			log.Printf("update ticket failed,err=%s", errT.Error())
+		}
+		s.notifyCreateFailure(ctx, larkCli, config, record, err)
 		return
 	}
+	if errT := s.ticketService.MarkCreated(record.ID, wiID); errT != nil {
+		log.Printf("update ticket failed,err=%s", errT.Error())
+	}
+	if errR := s.duplicateService.Record(config.ProjectKey, config.WorkItemTypeKey, wiID, contentText); errR != nil {
+		log.Printf("record ticket index failed,err=%s", errR.Error())
+	}
 
+	// 拉群与回复作为持久化的后续步骤执行，失败后按退避策略重试
+	payloadStep := &model.TicketStepPayload{
+		Title:          contentText,
+		ReporterName:   reporterDisplayName,
+		ReporterOpenID: reporterOpenID,
+		MentionOpenIDs: mentionOpenIDs(message),
+	}
 	//开启了自动拉群功能
 	if config.CreateGroupSwitch {
-		go func() {
-			titleCN := ("[工单]" + contentText)
-			titleEN := ("[Ticket]" + contentText)
-			reqCreateGroup := larkim.NewCreateChatReqBuilder().UserIdType("open_id").SetBotManager(true).
-				Body(
-					larkim.NewCreateChatReqBodyBuilder().
-						Name("[工单]" + contentText).I18nNames(&larkim.I18nNames{
-						ZhCn: &titleCN,
-						EnUs: &titleEN,
-						JaJp: &titleEN,
-					}).
-						OwnerId(reporterOpenID).
-						BotIdList([]string{config.BotID}).
-						Build()).
-				Build()
-			respGroup, err0 := larkCli.Im.Chat.Create(ctx, reqCreateGroup)
-			//这里失败只打日志不影响后续流程
-			if err0 != nil {
-				log.Printf("create group failed,err=%s", err0.Error())
-				return
-			}
-			if !respGroup.Success() {
-				log.Printf("create group failed,code=%s,msg=%s,requestID=%s", respGroup.Code, respGroup.Msg, respGroup.RequestId())
-				return
-			}
-			chatID := respGroup.Data.ChatId
-			if chatID == nil {
-				log.Printf("chat id is nil")
-				return
-			}
-			upFields := make([]*field.FieldValuePair, 0, 1)
-			upFields = append(upFields, &field.FieldValuePair{
-				FieldKey:   "group_type",
-				FieldValue: "bind"}, &field.FieldValuePair{
-				FieldKey:   "group_id",
-				FieldValue: *chatID})
-			wiUpdateReq := workitem.NewUpdateWorkItemReqBuilder().WorkItemTypeKey(config.WorkItemTypeKey).
-				ProjectKey(config.ProjectKey).UpdateFields(upFields).WorkItemID(wiResp.Data).Build()
-			wiUpdateResp, err0 := meegoCli.WorkItem.UpdateWorkItem(ctx, wiUpdateReq, core.WithUserKey(userKey))
-
-			if err0 != nil {
-				log.Printf("update workitem group failed,err=%s, logid=%s", err0.Error(), wiUpdateResp.Header.Get("x-tt-logid"))
-				return
-			}
-			if !wiUpdateResp.Success() {
-				log.Printf("update workitem group failed,code=%s", wiUpdateResp.Error())
-			}
-		}()
-
+		s.enqueueStep(record.ID, config, wiID, model.TicketStepCreateGroup, payloadStep)
 	}
 
 	//开启了创建后反馈工单链接功能时
 	if config.ReplySwitch {
-		newID := wiResp.Data
-		go func() {
-			//获取simplename，获取工作项apiname
-			var simpleName string
-
-			respProj, errP := meegoCli.Project.GetProjectDetail(ctx,
-				project.NewGetProjectDetailReqBuilder().ProjectKeys([]string{config.ProjectKey}).UserKey(userKey).Build(),
-				core.WithUserKey(userKey))
-			if errP != nil {
-				log.Printf("get project info failed,err=%s", errP.Error())
-				return
-			}
-			if p, ok := respProj.Data[config.ProjectKey]; ok {
-				simpleName = p.SimpleName
-			}
-			wiURL := fmt.Sprintf("%s/%s/%s/detail/%d", s.feishuCfg.ProjectAPIHost, simpleName, config.WorkItemAPIName, newID)
-			cnContent := make([][]map[string]interface{}, 0, 2)
-			cnContentLine1 := make([]map[string]interface{}, 0, 2)
-			cnContentLine1 = append(cnContentLine1, map[string]interface{}{
-				"tag":   "text",
-				"text":  "工单内容: ",
-				"style": []string{"bold"},
-			})
-			cnContentLine1 = append(cnContentLine1, map[string]interface{}{
-				"tag":  "text",
-				"text": content,
-			})
-			cnContentLine2 := make([]map[string]interface{}, 0, 2)
-			cnContentLine2 = append(cnContentLine2, map[string]interface{}{
-				"tag":   "text",
-				"text":  "工单链接: ",
-				"style": []string{"bold"},
-			})
-			cnContentLine2 = append(cnContentLine2, map[string]interface{}{
-				"tag":  "a",
-				"text": "查看详情",
-				"href": wiURL,
-			})
-			cnContent = append(cnContent, cnContentLine1, cnContentLine2)
-
-			enContent := make([][]map[string]interface{}, 0, 2)
-			enContentLine1 := make([]map[string]interface{}, 0, 2)
-			enContentLine1 = append(enContentLine1, map[string]interface{}{
-				"tag":   "text",
-				"text":  "Ticket Content: ",
-				"style": []string{"bold"},
-			})
-			enContentLine1 = append(enContentLine1, map[string]interface{}{
-				"tag":  "text",
-				"text": content,
-			})
-			enContentLine2 := make([]map[string]interface{}, 0, 2)
-			enContentLine2 = append(enContentLine2, map[string]interface{}{
-				"tag":   "text",
-				"text":  "Ticket Link: ",
-				"style": []string{"bold"},
-			})
-			enContentLine2 = append(enContentLine2, map[string]interface{}{
-				"tag":  "a",
-				"text": "View Detail",
-				"href": wiURL,
-			})
-			enContent = append(enContent, enContentLine1, enContentLine2)
-			msg := map[string]interface{}{
-				"zh_cn": map[string]interface{}{
-					"title":   "🆕工单创建成功️",
-					"content": cnContent,
-				},
-				"en_us": map[string]interface{}{
-					"title":   "🆕Ticket created",
-					"content": enContent,
-				},
-			}
-			msgStr, _ := json.Marshal(msg)
-			respIm, err1 := larkCli.Im.Message.Create(ctx,
-				larkim.NewCreateMessageReqBuilder().
-					ReceiveIdType("open_id").
-					Body(
-						larkim.NewCreateMessageReqBodyBuilder().
-							ReceiveId(reporterOpenID).
-							MsgType("post").
-							Content(string(msgStr)).
-							Build()).
-					Build())
-			if err1 != nil {
-				log.Printf("send msg failed,err=%s", err1.Error())
-				return
-			}
-			if !respIm.Success() {
-				log.Printf("send msg failed,code=%s,msg=%s,requestID=%s", respIm.Code, respIm.Msg, respIm.RequestId())
-				return
-			}
-		}()
+		s.enqueueStep(record.ID, config, wiID, model.TicketStepReply, payloadStep)
 	}
 
 	return
 
 }
 
-func (s *EventService) GetFeishuProjectClient() (*projSDK.Client, error) {
+// TestConnection 模拟一条消息走完解析、路由、字段映射与创建流程，用于验证整条链路
+func (s *EventService) TestConnection(ctx context.Context, req *model.TestConfigRequest) (*model.TestConfigResponse, error) {
+	if req == nil {
+		return nil, errors.New("invalid test request")
+	}
+	if req.UserKey == "" {
+		return nil, errors.New("caller user_key is required")
+	}
+	resp := &model.TestConfigResponse{
+		DryRun: getBoolValue(req.DryRun, true),
+		Steps:  make([]*model.TestConfigStep, 0, 6),
+	}
+	step := func(name string, err error) bool {
+		st := &model.TestConfigStep{Name: name, Success: err == nil}
+		if err != nil {
+			st.ErrMsg = err.Error()
+		}
+		resp.Steps = append(resp.Steps, st)
+		return err == nil
+	}
+
+	config, err := s.configService.GetConfigByProjectKey(req.ProjectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// 解析消息内容，与飞书回调中的文本消息格式保持一致
+	rawContent, _ := json.Marshal(model.TextContent{Text: req.Message})
+	content, err := s.parseMessageContent(string(rawContent))
+	if !step("parse", err) {
+		return resp, nil
+	}
+	resp.ContentText = extractContentText(content.Text)
+
+	// 按事件订阅地址中的签名路由到配置，与回调处理保持一致
+	signature := req.Signature
+	if signature == "" {
+		signature = config.Signature
+	}
+	if signature == "" {
+		err = errors.New("webhook signature has not been generated")
+	} else if routed, errR := s.configService.GetConfigBySignature(signature); errR != nil {
+		err = fmt.Errorf("signature not found: %w", errR)
+	} else if routed.ProjectKey != config.ProjectKey {
+		err = fmt.Errorf("signature routed to project_key=%s", routed.ProjectKey)
+	}
+	if !step("route", err) {
+		return resp, nil
+	}
+	if !config.Enabled {
+		err = errors.New("bot connection is disabled")
+	}
+	if !step("enabled", err) {
+		return resp, nil
+	}
+
+	larkCli, err := s.getLarkSDKCli(config)
+	if !step("lark_client", err) {
+		return resp, nil
+	}
+	// 演练时允许不指定发送者；实际创建工单必须使用真实发送者，避免产生无法追溯的工单
+	resp.ReporterName = "smart_elf_test"
+	if req.SenderOpenID == "" && !resp.DryRun {
+		err = errors.New("sender_open_id is required when dry_run is false")
+	} else if req.SenderOpenID != "" {
+		resp.ReporterName, err = s.getReporterName(ctx, larkCli, req.SenderOpenID)
+	}
+	if !step("reporter", err) {
+		return resp, nil
+	}
+
+	payload := s.buildWorkItemPayload(config, resp.ReporterName, req.SenderOpenID, resp.ContentText)
+	resp.Payload = payload
+	step("field_mapping", nil)
+	if resp.DryRun {
+		resp.Success = true
+		return resp, nil
+	}
+
+	// 测试工单以调用方身份创建与删除
+	operator := *config
+	operator.APIUserKey = req.UserKey
+	config = &operator
+	meegoCli, err := s.GetFeishuProjectClient()
+	if err != nil {
+		log.Printf("错误: 获取飞书项目客户端失败: %v, project_key=%s", err, config.ProjectKey)
+	}
+	if !step("meego_client", err) {
+		return resp, nil
+	}
+	resp.WorkItemID, err = s.createWorkItem(ctx, meegoCli, config, payload)
+	if !step("create", err) {
+		return resp, nil
+	}
+	if wiURL, errURL := s.buildWorkItemURL(ctx, meegoCli, config, resp.WorkItemID); errURL == nil {
+		resp.WorkItemURL = wiURL
+	}
+
+	if req.DeleteAfterCreate {
+		err = s.deleteWorkItem(ctx, meegoCli, config, resp.WorkItemID)
+		if !step("delete", err) {
+			return resp, nil
+		}
+		resp.Deleted = true
+	}
+	resp.Success = true
+	return resp, nil
+}
+
+// deleteWorkItem 删除工作项
+func (s *EventService) deleteWorkItem(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, workItemID int64) error {
+	delReq := workitem.NewDeleteWorkItemReqBuilder().ProjectKey(config.ProjectKey).
+		WorkItemTypeKey(config.WorkItemTypeKey).WorkItemID(workItemID).Build()
+	callCtx, cancel := s.callCtx(ctx, apiClassWorkItem)
+	defer cancel()
+	delResp, err := meegoCli.WorkItem.DeleteWorkItem(callCtx, delReq, s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return err
+	}
+	if !delResp.Success() {
+		return delResp.CodeError
+	}
+	return nil
+}
+
+// findOpenDuplicate 查找与标题相似且仍未关闭的同类型工单，已关闭、终止或删除的工单从索引中移除，不作为合并目标
+func (s *EventService) findOpenDuplicate(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, title string) (*model.TicketIndexEntry, error) {
+	matches, err := s.duplicateService.FindDuplicates(config.ProjectKey, config.WorkItemTypeKey, title)
+	if err != nil || len(matches) == 0 {
+		return nil, err
+	}
+	ids := make([]int64, 0, len(matches))
+	for _, m := range matches {
+		ids = append(ids, m.WorkItemID)
+	}
+	items, err := s.queryWorkItems(ctx, meegoCli, config, config.WorkItemTypeKey, ids, nil)
+	if err != nil {
+		return nil, err
+	}
+	infos := make(map[int64]*workitem.WorkItemInfo, len(items))
+	for _, wi := range items {
+		infos[wi.ID] = wi
+	}
+	for _, m := range matches {
+		if wi, ok := infos[m.WorkItemID]; ok && !isWorkItemClosed(wi) {
+			log.Printf("信息: 命中重复工单: project_key=%s, work_item_id=%d", config.ProjectKey, m.WorkItemID)
+			return m, nil
+		}
+		if errF := s.duplicateService.Forget(m.ProjectKey, m.WorkItemTypeKey, m.WorkItemID); errF != nil {
+			log.Printf("forget closed ticket index failed,err=%s", errF.Error())
+		}
+	}
+	return nil, nil
+}
+
+// mergeIntoTicket 将重复反馈合并到已有工单：按配置加关注人或评论，并告知提单人。
+// reporterCfg 为以提单人身份调用飞书项目的配置，见 reporterConfig
+func (s *EventService) mergeIntoTicket(ctx context.Context, meegoCli *projSDK.Client, larkCli *lark.Client, config, reporterCfg *model.AppConfig,
+	dup *model.TicketIndexEntry, reporterName, reporterOpenID, reporterUserKey, contentText string) error {
+	merged := false
+	if s.duplicateService.Action() == DuplicateActionWatcher {
+		err := errors.New("meego user not found for reporter")
+		if reporterUserKey != "" {
+			err = s.addWatcher(ctx, meegoCli, reporterCfg, dup.WorkItemTypeKey, dup.WorkItemID, reporterUserKey)
+		}
+		if err != nil {
+			log.Printf("add watcher failed, fallback to comment,err=%s", err.Error())
+		} else {
+			merged = true
+		}
+	}
+	if !merged {
+		commentText := fmt.Sprintf("%s 也反馈了该问题: %s", reporterName, contentText)
+		if err := s.createComment(ctx, meegoCli, config, dup.WorkItemTypeKey, dup.WorkItemID, commentText, reporterCfg.APIUserKey); err != nil {
+			return err
+		}
+	}
+
+	wiURL, err := s.buildWorkItemURL(ctx, meegoCli, config, dup.WorkItemID)
+	if err != nil {
+		log.Printf("get project info failed,err=%s", err.Error())
+	}
+	text := fmt.Sprintf("你反馈的问题与已有工单相似，已合并至: %s\n%s\nYour report matches an existing ticket and has been merged into it.",
+		dup.Title, wiURL)
+	if err := s.sendTextMessage(ctx, larkCli, reporterOpenID, text); err != nil {
+		log.Printf("send merged msg failed,err=%s", err.Error())
+	}
+	return nil
+}
+
+// sendTicketCreatedReply 向提单人发送工单创建成功的消息与工单链接
+func (s *EventService) sendTicketCreatedReply(ctx context.Context, larkCli *lark.Client, meegoCli *projSDK.Client,
+	config *model.AppConfig, workItemID int64, title, reporterOpenID string) error {
+	wiURL, err := s.buildWorkItemURL(ctx, meegoCli, config, workItemID)
+	if err != nil {
+		return err
+	}
+	cnContent := make([][]map[string]interface{}, 0, 2)
+	cnContentLine1 := make([]map[string]interface{}, 0, 2)
+	cnContentLine1 = append(cnContentLine1, map[string]interface{}{
+		"tag":   "text",
+		"text":  "工单内容: ",
+		"style": []string{"bold"},
+	})
+	cnContentLine1 = append(cnContentLine1, map[string]interface{}{
+		"tag":  "text",
+		"text": title,
+	})
+	cnContentLine2 := make([]map[string]interface{}, 0, 2)
+	cnContentLine2 = append(cnContentLine2, map[string]interface{}{
+		"tag":   "text",
+		"text":  "工单链接: ",
+		"style": []string{"bold"},
+	})
+	cnContentLine2 = append(cnContentLine2, map[string]interface{}{
+		"tag":  "a",
+		"text": "查看详情",
+		"href": wiURL,
+	})
+	cnContent = append(cnContent, cnContentLine1, cnContentLine2)
 
-	clientV2 := projSDK.NewClient(s.feishuCfg.PluginID, s.feishuCfg.PluginSecret,
-		projSDK.WithOpenBaseUrl(s.feishuCfg.ProjectAPIHost), projSDK.WithAccessTokenType(core.AccessTokenTypePlugin))
+	enContent := make([][]map[string]interface{}, 0, 2)
+	enContentLine1 := make([]map[string]interface{}, 0, 2)
+	enContentLine1 = append(enContentLine1, map[string]interface{}{
+		"tag":   "text",
+		"text":  "Ticket Content: ",
+		"style": []string{"bold"},
+	})
+	enContentLine1 = append(enContentLine1, map[string]interface{}{
+		"tag":  "text",
+		"text": title,
+	})
+	enContentLine2 := make([]map[string]interface{}, 0, 2)
+	enContentLine2 = append(enContentLine2, map[string]interface{}{
+		"tag":   "text",
+		"text":  "Ticket Link: ",
+		"style": []string{"bold"},
+	})
+	enContentLine2 = append(enContentLine2, map[string]interface{}{
+		"tag":  "a",
+		"text": "View Detail",
+		"href": wiURL,
+	})
+	enContent = append(enContent, enContentLine1, enContentLine2)
+	msg := map[string]interface{}{
+		"zh_cn": map[string]interface{}{
+			"title":   "🆕工单创建成功️",
+			"content": cnContent,
+		},
+		"en_us": map[string]interface{}{
+			"title":   "🆕Ticket created",
+			"content": enContent,
+		},
+	}
+	msgStr, _ := json.Marshal(msg)
+	_, err = s.sendMessage(ctx, larkCli, "open_id", reporterOpenID, "post", string(msgStr))
+	return err
+}
 
-	return clientV2, nil
+// sendTextMessage 以机器人身份给用户发送文本消息
+func (s *EventService) sendTextMessage(ctx context.Context, larkCli *lark.Client, openID, text string) error {
+	msgStr, _ := json.Marshal(model.TextContent{Text: text})
+	_, err := s.sendMessage(ctx, larkCli, "open_id", openID, "text", string(msgStr))
+	return err
+}
+
+// sendMessage 以机器人身份发送消息，返回消息ID
+func (s *EventService) sendMessage(ctx context.Context, larkCli *lark.Client, receiveIDType, receiveID, msgType, content string) (string, error) {
+	callCtx, cancel := s.callCtx(ctx, apiClassIM)
+	defer cancel()
+	respIm, err := larkCli.Im.Message.Create(callCtx,
+		larkim.NewCreateMessageReqBuilder().
+			ReceiveIdType(receiveIDType).
+			Body(
+				larkim.NewCreateMessageReqBodyBuilder().
+					ReceiveId(receiveID).
+					MsgType(msgType).
+					Content(content).
+					Build()).
+			Build())
+	if err != nil {
+		return "", err
+	}
+	if !respIm.Success() {
+		return "", fmt.Errorf("code=%d,msg=%s,requestID=%s", respIm.Code, respIm.Msg, respIm.RequestId())
+	}
+	if respIm.Data == nil || respIm.Data.MessageId == nil {
+		return "", nil
+	}
+	return *respIm.Data.MessageId, nil
+}
+
+// mentionOpenIDs 获取消息中@到的用户 open_id
+func mentionOpenIDs(message *model.LarkMessage) []string {
+	ids := make([]string, 0, len(message.Mentions))
+	for _, m := range message.Mentions {
+		if m != nil && m.ID != nil && m.ID.OpenID != "" {
+			ids = append(ids, m.ID.OpenID)
+		}
+	}
+	return ids
+}
+
+// messageThreadID 获取消息所在话题ID，没有话题时使用根消息ID
+func messageThreadID(message *model.LarkMessage) string {
+	if message.ThreadID != "" {
+		return message.ThreadID
+	}
+	return message.RootID
+}
+
+// extractContentText 去除消息中的@占位符，得到工单标题
+func extractContentText(text string) string {
+	reg := regexp.MustCompile(`@_user_[0-9]+`)
+	return strings.TrimSpace(reg.ReplaceAllString(text, ""))
+}
+
+// getReporterName 通过飞书通讯录获取提单人名称
+func (s *EventService) getReporterName(ctx context.Context, larkCli *lark.Client, openID string) (string, error) {
+	callCtx, cancel := s.callCtx(ctx, apiClassContact)
+	defer cancel()
+	userResp, err := larkCli.Contact.User.Get(callCtx, larkcontact.NewGetUserReqBuilder().
+		UserIdType("open_id").UserId(openID).Build())
+	if err != nil {
+		return "", err
+	}
+	if !userResp.Success() {
+		return "", fmt.Errorf("code=%d,msg=%s,requestID=%s", userResp.Code, userResp.Msg, userResp.RequestId())
+	}
+	if userResp.Data == nil || userResp.Data.User == nil || userResp.Data.User.Name == nil {
+		return "", errors.New("reporterDisplayName is nil")
+	}
+	return *userResp.Data.User.Name, nil
+}
+
+// buildWorkItemPayload 根据配置与消息内容组装创建工作项的请求体
+func (s *EventService) buildWorkItemPayload(config *model.AppConfig, reporterName, reporterOpenID, contentText string) *workitem.CreateWorkItemReqBody {
+	fields := make([]*field.FieldValuePair, 0, 1)
+	fields = append(fields, &field.FieldValuePair{
+		FieldValue: fmt.Sprintf("%s###%s", reporterName, reporterOpenID),
+		FieldKey:   config.CreatorFieldKey})
+	return &workitem.CreateWorkItemReqBody{
+		WorkItemTypeKey: config.WorkItemTypeKey,
+		FieldValuePairs: fields,
+		TemplateID:      config.WorkItemTemplateID,
+		Name:            contentText,
+	}
+}
+
+// createWorkItem 调用飞书项目创建工作项，返回工作项ID
+func (s *EventService) createWorkItem(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, payload *workitem.CreateWorkItemReqBody) (int64, error) {
+	wiReq := workitem.NewCreateWorkItemReqBuilder().WorkItemTypeKey(payload.WorkItemTypeKey).
+		ProjectKey(config.ProjectKey).Name(payload.Name).FieldValuePairs(payload.FieldValuePairs).TemplateID(payload.TemplateID).Build()
+	callCtx, cancel := s.callCtx(ctx, apiClassWorkItem)
+	defer cancel()
+	wiResp, err := meegoCli.WorkItem.CreateWorkItem(callCtx, wiReq, s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return 0, err
+	}
+	if !wiResp.Success() {
+		return 0, wiResp.CodeError
+	}
+	return wiResp.Data, nil
+}
+
+// buildWorkItemURL 获取空间simplename（优先读缓存）并拼接工作项详情链接
+func (s *EventService) buildWorkItemURL(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, workItemID int64) (string, error) {
+	proj, err := s.metadataService.GetProject(ctx, config)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%s/detail/%d", s.clientRegistry.FeishuConfig().ProjectAPIHost, proj.SimpleName, config.WorkItemAPIName, workItemID), nil
+}
+
+// GetFeishuProjectClient 获取按插件缓存的飞书项目SDK客户端
+func (s *EventService) GetFeishuProjectClient() (*projSDK.Client, error) {
+	return s.clientRegistry.ProjectClient()
 }
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"smart_elf_standalone/internal/model"
+	"time"
+)
+
+// enqueueStep 记录工单后续步骤并异步执行
+func (s *EventService) enqueueStep(ticketID uint, config *model.AppConfig, workItemID int64, stepType string, payload *model.TicketStepPayload) {
+	payloadStr, _ := json.Marshal(payload)
+	step := &model.TicketStep{
+		TicketID:        ticketID,
+		ProjectKey:      config.ProjectKey,
+		WorkItemTypeKey: config.WorkItemTypeKey,
+		WorkItemID:      workItemID,
+		StepType:        stepType,
+		Payload:         string(payloadStr),
+	}
+	if err := s.stepService.CreateStep(step); err != nil {
+		// 无法持久化时仍尝试执行一次，失败只打日志
+		s.goTracked(func(ctx context.Context) {
+			if _, errE := s.executeStep(ctx, step); errE != nil {
+				log.Printf("execute step failed,step=%s,err=%s", stepType, errE.Error())
+			}
+		})
+		return
+	}
+	// 服务停机中不再执行，步骤保持待执行状态，下次启动时恢复
+	started := s.goTracked(func(ctx context.Context) {
+		if errR := s.runStep(ctx, step); errR != nil {
+			log.Printf("run step failed,step_id=%d,step=%s,err=%s", step.ID, stepType, errR.Error())
+		}
+	})
+	if !started {
+		log.Printf("信息: 服务停机中，步骤延后执行: step_id=%d, step=%s", step.ID, stepType)
+	}
+}
+
+// runStep 领取并执行步骤，失败时记录原因并安排重试
+func (s *EventService) runStep(ctx context.Context, step *model.TicketStep) error {
+	claimed, err := s.stepService.Claim(step)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+	result, err := s.executeStep(ctx, step)
+	if err != nil {
+		// 停机取消导致的失败不计入重试次数
+		if ctx.Err() != nil {
+			if errR := s.stepService.Release(step); errR != nil {
+				log.Printf("错误: 释放步骤失败: %v, id=%d", errR, step.ID)
+			}
+			return err
+		}
+		if errM := s.stepService.MarkFailed(step, err); errM != nil {
+			log.Printf("错误: 记录步骤失败状态失败: %v, id=%d", errM, step.ID)
+		}
+		return err
+	}
+	return s.stepService.MarkSucceeded(step, result)
+}
+
+// executeStep 执行单个后续步骤，返回步骤产出（如群ID）
+func (s *EventService) executeStep(ctx context.Context, step *model.TicketStep) (string, error) {
+	var payload model.TicketStepPayload
+	if err := json.Unmarshal([]byte(step.Payload), &payload); err != nil {
+		return "", fmt.Errorf("invalid step payload: %w", err)
+	}
+	config, err := s.configService.GetConfigByProjectKey(step.ProjectKey)
+	if err != nil {
+		return "", err
+	}
+	larkCli, err := s.getLarkSDKCli(config)
+	if err != nil {
+		return "", err
+	}
+	meegoCli, err := s.GetFeishuProjectClient()
+	if err != nil {
+		return "", err
+	}
+
+	switch step.StepType {
+	case model.TicketStepCreateGroup:
+		return s.createTicketGroup(ctx, larkCli, meegoCli, config, &ticketGroup{
+			WorkItemID:     step.WorkItemID,
+			Title:          payload.Title,
+			ReporterName:   payload.ReporterName,
+			ReporterOpenID: payload.ReporterOpenID,
+			MentionOpenIDs: payload.MentionOpenIDs,
+		})
+	case model.TicketStepReply:
+		return "", s.sendTicketCreatedReply(ctx, larkCli, meegoCli, config, step.WorkItemID, payload.Title, payload.ReporterOpenID)
+	default:
+		return "", fmt.Errorf("unknown step type: %s", step.StepType)
+	}
+}
+
+// StartStepRetrier 恢复上次停机时未完成的步骤，并按配置的间隔重试到期的失败步骤，服务停机后退出
+func (s *EventService) StartStepRetrier() {
+	if err := s.stepService.RequeueUnfinishedSteps(); err != nil {
+		log.Printf("错误: 恢复未完成的步骤失败: %v", err)
+	}
+	interval := s.stepService.RetryInterval()
+	s.goTracked(func(ctx context.Context) {
+		s.RetryDueSteps(ctx)
+		if interval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.lifecycle.stopCh:
+				return
+			case <-ticker.C:
+				s.RetryDueSteps(ctx)
+			}
+		}
+	})
+	if interval > 0 {
+		log.Printf("信息: 工单后续步骤定时重试已启动: interval=%s", interval)
+	}
+}
+
+// RetryDueSteps 重试所有已到重试时间的步骤
+func (s *EventService) RetryDueSteps(ctx context.Context) {
+	steps, err := s.stepService.ListDueSteps()
+	if err != nil {
+		log.Printf("错误: 查询待重试步骤失败: %v", err)
+		return
+	}
+	for _, step := range steps {
+		if s.stopping() {
+			return
+		}
+		if err := s.runStep(ctx, step); err != nil {
+			log.Printf("错误: 重试步骤失败: %v, id=%d, step=%s, attempts=%d", err, step.ID, step.StepType, step.Attempts)
+		}
+	}
+}
+
+// stepReplayTimeout 单次重放请求的执行时间上限
+const stepReplayTimeout = 60 * time.Second
+
+// ReplaySteps 重放失败的步骤并返回执行后的状态。超过 stepReplayTimeout 后未执行的步骤记为失败，可再次重放
+func (s *EventService) ReplaySteps(ctx context.Context, req *model.TicketStepReplayRequest) ([]*model.TicketStep, error) {
+	steps, err := s.stepService.ResetFailedSteps(req.ProjectKey, req.StepIDs)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, stepReplayTimeout)
+	defer cancel()
+	for _, step := range steps {
+		if ctx.Err() != nil {
+			if errM := s.stepService.MarkFailed(step, ctx.Err()); errM != nil {
+				log.Printf("错误: 记录步骤失败状态失败: %v, id=%d", errM, step.ID)
+			}
+			continue
+		}
+		if err := s.runStep(ctx, step); err != nil {
+			log.Printf("错误: 重放步骤失败: %v, id=%d, step=%s", err, step.ID, step.StepType)
+		}
+	}
+	return steps, nil
+}
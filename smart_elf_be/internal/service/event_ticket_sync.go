@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"log"
+	"smart_elf_standalone/internal/model"
+	"time"
+
+	projSDK "github.com/larksuite/project-oapi-sdk-golang"
+	"github.com/larksuite/project-oapi-sdk-golang/service/workitem"
+)
+
+// 工单状态同步参数
+const (
+	ticketSyncPageSize  = 500
+	ticketSyncBatchSize = 50
+)
+
+// SyncTicketStates 从飞书项目同步未关闭工单的首次响应与关闭时间，按 id 分页遍历全部未关闭工单，
+// 单批查询失败时记录日志并继续同步其他批次
+func (s *EventService) SyncTicketStates(ctx context.Context, projectKey string) error {
+	config, err := s.configService.GetConfigByProjectKey(projectKey)
+	if err != nil {
+		return err
+	}
+	meegoCli, err := s.GetFeishuProjectClient()
+	if err != nil {
+		return err
+	}
+
+	var afterID uint
+	for {
+		tickets, err := s.ticketService.ListUnclosedTickets(projectKey, afterID, ticketSyncPageSize)
+		if err != nil {
+			log.Printf("错误: 查询待同步工单失败: %v", err)
+			return err
+		}
+		if len(tickets) == 0 {
+			return nil
+		}
+		afterID = tickets[len(tickets)-1].ID
+
+		byType := make(map[string][]*model.Ticket)
+		for _, t := range tickets {
+			byType[t.WorkItemTypeKey] = append(byType[t.WorkItemTypeKey], t)
+		}
+		for typeKey, list := range byType {
+			for start := 0; start < len(list); start += ticketSyncBatchSize {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				end := start + ticketSyncBatchSize
+				if end > len(list) {
+					end = len(list)
+				}
+				s.syncTicketBatch(ctx, meegoCli, config, typeKey, list[start:end])
+			}
+		}
+		if len(tickets) < ticketSyncPageSize {
+			return nil
+		}
+	}
+}
+
+// syncTicketBatch 批量查询同一工作项类型的工单状态并更新台账
+func (s *EventService) syncTicketBatch(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, typeKey string, batch []*model.Ticket) {
+	ids := make([]int64, 0, len(batch))
+	for _, t := range batch {
+		ids = append(ids, t.WorkItemID)
+	}
+	items, err := s.queryWorkItems(ctx, meegoCli, config, typeKey, ids, nil)
+	if err != nil {
+		log.Printf("错误: 同步工作项状态失败: %v, work_item_type_key=%s, work_item_ids=%v", err, typeKey, ids)
+		return
+	}
+	infos := make(map[int64]*workitem.WorkItemInfo, len(items))
+	for _, wi := range items {
+		infos[wi.ID] = wi
+	}
+	for _, t := range batch {
+		wi, ok := infos[t.WorkItemID]
+		if !ok {
+			continue
+		}
+		firstResponseAt, closedAt := workItemStateTimes(wi)
+		if t.FirstResponseAt != nil {
+			firstResponseAt = nil
+		}
+		if err := s.ticketService.UpdateStateTimes(t.ID, firstResponseAt, closedAt); err != nil {
+			log.Printf("错误: 更新工单状态时间失败: %v, id=%d", err, t.ID)
+		}
+	}
+}
+
+// workItemStateTimes 根据工作项状态流转推算首次响应时间（首次离开初始状态）与关闭时间
+func workItemStateTimes(wi *workitem.WorkItemInfo) (firstResponseAt, closedAt *time.Time) {
+	if status := wi.WorkItemStatus; status != nil {
+		for _, h := range status.History {
+			if h != nil && !h.IsInitState && h.UpdatedAt > 0 {
+				if firstResponseAt == nil || h.UpdatedAt < firstResponseAt.UnixMilli() {
+					firstResponseAt = msToTime(h.UpdatedAt)
+				}
+			}
+		}
+		if firstResponseAt == nil && !status.IsInitState && status.UpdatedAt > 0 {
+			firstResponseAt = msToTime(status.UpdatedAt)
+		}
+		if status.IsArchivedState && status.UpdatedAt > 0 {
+			closedAt = msToTime(status.UpdatedAt)
+		}
+	}
+	// 节点流工作项没有状态记录，使用第二个节点的开始时间作为首次响应
+	if firstResponseAt == nil && len(wi.StateTimes) > 1 && wi.StateTimes[1].StartTime > 0 {
+		firstResponseAt = msToTime(wi.StateTimes[1].StartTime)
+	}
+	if closedAt == nil && wi.DeletedAt > 0 {
+		closedAt = msToTime(wi.DeletedAt)
+	}
+	// 终止的工作项没有终止时间，使用最后更新时间
+	if closedAt == nil && isWorkItemAborted(wi) && wi.UpdatedAt > 0 {
+		closedAt = msToTime(wi.UpdatedAt)
+	}
+	return firstResponseAt, closedAt
+}
+
+// msToTime 将毫秒时间戳转换为时间
+func msToTime(ms int64) *time.Time {
+	t := time.UnixMilli(ms)
+	return &t
+}
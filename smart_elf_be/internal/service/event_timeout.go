@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"smart_elf_standalone/pkg/config"
+	"time"
+)
+
+// 外部接口类别，每类可单独配置超时时间
+const (
+	apiClassContact  = "contact"
+	apiClassIM       = "im"
+	apiClassProject  = "project"
+	apiClassWorkItem = "work_item"
+)
+
+// defaultAPITimeout 未配置时外部接口的超时时间
+const defaultAPITimeout = 10 * time.Second
+
+// callCtx 为单次外部接口调用派生带超时的 context
+func (s *EventService) callCtx(ctx context.Context, class string) (context.Context, context.CancelFunc) {
+	return apiCallCtx(ctx, s.clientRegistry.FeishuConfig().Timeout, class)
+}
+
+// apiCallCtx 按接口类别的超时配置派生 context
+func apiCallCtx(ctx context.Context, cfg config.APITimeoutConfig, class string) (context.Context, context.CancelFunc) {
+	var seconds int
+	switch class {
+	case apiClassContact:
+		seconds = cfg.ContactSeconds
+	case apiClassIM:
+		seconds = cfg.IMSeconds
+	case apiClassProject:
+		seconds = cfg.ProjectSeconds
+	case apiClassWorkItem:
+		seconds = cfg.WorkItemSeconds
+	}
+	timeout := time.Duration(seconds) * time.Second
+	if timeout <= 0 {
+		timeout = defaultAPITimeout
+	}
+	return context.WithTimeout(ctx, timeout)
+}
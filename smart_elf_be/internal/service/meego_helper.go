@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"smart_elf_standalone/internal/model"
+
+	projSDK "github.com/larksuite/project-oapi-sdk-golang"
+	"github.com/larksuite/project-oapi-sdk-golang/core"
+	"github.com/larksuite/project-oapi-sdk-golang/service/comment"
+	"github.com/larksuite/project-oapi-sdk-golang/service/field"
+	"github.com/larksuite/project-oapi-sdk-golang/service/user"
+	"github.com/larksuite/project-oapi-sdk-golang/service/workitem"
+)
+
+// watchersFieldKey 工作项关注人字段
+const watchersFieldKey = "watchers"
+
+// meegoUserOption 以指定用户身份调用飞书项目接口，见 userRequestOption
+func (s *EventService) meegoUserOption(ctx context.Context, userKey string) core.RequestOptionFunc {
+	return userRequestOption(ctx, s.userTokenService, userKey)
+}
+
+// userRequestOption 以指定用户身份调用飞书项目接口。
+// 用户已通过插件授权时使用其用户令牌，操作记录归属该用户；否则使用插件令牌并携带 X-User-Key
+func userRequestOption(ctx context.Context, userTokenService *UserTokenService, userKey string) core.RequestOptionFunc {
+	token, err := userTokenService.AccessToken(ctx, userKey)
+	if err != nil {
+		if !errors.Is(err, ErrUserNotAuthorized) {
+			log.Printf("get user access token failed,user_key=%s,err=%s", userKey, err.Error())
+		}
+		return core.WithUserKey(userKey)
+	}
+	return func(option *core.RequestOption) {
+		core.WithUserKey(userKey)(option)
+		core.WithAccessToken(token)(option)
+	}
+}
+
+// reporterConfig 返回以提单人身份调用飞书项目的配置副本及提单人的 user_key（未找到时为空）。
+// 提单人已授权插件时以其身份操作，否则沿用配置中的 user_key，避免因提单人缺少空间权限而失败
+func (s *EventService) reporterConfig(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, unionID string) (*model.AppConfig, string) {
+	if unionID == "" {
+		return config, ""
+	}
+	userKey, err := s.getMeegoUserKey(ctx, meegoCli, config, unionID)
+	if err != nil {
+		log.Printf("get reporter user key failed,err=%s", err.Error())
+		return config, ""
+	}
+	if _, err := s.userTokenService.AccessToken(ctx, userKey); err != nil {
+		return config, userKey
+	}
+	reporterConfig := *config
+	reporterConfig.APIUserKey = userKey
+	return &reporterConfig, userKey
+}
+
+// getMeegoUserKey 通过飞书 union_id 查询飞书项目的 user_key
+func (s *EventService) getMeegoUserKey(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, unionID string) (string, error) {
+	if unionID == "" {
+		return "", errors.New("empty union_id")
+	}
+	callCtx, cancel := s.callCtx(ctx, apiClassProject)
+	defer cancel()
+	resp, err := meegoCli.User.QueryUserDetail(callCtx,
+		user.NewQueryUserDetailReqBuilder().OutIDs([]string{unionID}).Build(),
+		s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success() {
+		return "", resp.CodeError
+	}
+	if len(resp.Data) == 0 || resp.Data[0].UserKey == "" {
+		return "", fmt.Errorf("meego user not found, union_id=%s", unionID)
+	}
+	return resp.Data[0].UserKey, nil
+}
+
+// getMeegoUsers 按 user_key 批量查询飞书项目用户
+func (s *EventService) getMeegoUsers(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, userKeys []string) ([]*user.UserBasicInfo, error) {
+	callCtx, cancel := s.callCtx(ctx, apiClassProject)
+	defer cancel()
+	resp, err := meegoCli.User.QueryUserDetail(callCtx,
+		user.NewQueryUserDetailReqBuilder().UserKeys(userKeys).Build(),
+		s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success() {
+		return nil, resp.CodeError
+	}
+	return resp.Data, nil
+}
+
+// queryWorkItem 查询单个工作项详情
+func (s *EventService) queryWorkItem(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, workItemTypeKey string, workItemID int64, fields []string) (*workitem.WorkItemInfo, error) {
+	items, err := s.queryWorkItems(ctx, meegoCli, config, workItemTypeKey, []int64{workItemID}, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("work item not found, id=%d", workItemID)
+	}
+	return items[0], nil
+}
+
+// queryWorkItems 批量查询工作项详情
+func (s *EventService) queryWorkItems(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, workItemTypeKey string, workItemIDs []int64, fields []string) ([]*workitem.WorkItemInfo, error) {
+	req := workitem.NewQueryWorkItemDetailReqBuilder().ProjectKey(config.ProjectKey).
+		WorkItemTypeKey(workItemTypeKey).WorkItemIDs(workItemIDs).Fields(fields).Build()
+	callCtx, cancel := s.callCtx(ctx, apiClassWorkItem)
+	defer cancel()
+	resp, err := meegoCli.WorkItem.QueryWorkItemDetail(callCtx, req, s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success() {
+		return nil, resp.CodeError
+	}
+	return resp.Data, nil
+}
+
+// updateWorkItemFields 更新工作项字段
+func (s *EventService) updateWorkItemFields(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, workItemTypeKey string, workItemID int64, fields []*field.FieldValuePair) error {
+	req := workitem.NewUpdateWorkItemReqBuilder().WorkItemTypeKey(workItemTypeKey).
+		ProjectKey(config.ProjectKey).UpdateFields(fields).WorkItemID(workItemID).Build()
+	callCtx, cancel := s.callCtx(ctx, apiClassWorkItem)
+	defer cancel()
+	resp, err := meegoCli.WorkItem.UpdateWorkItem(callCtx, req, s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return err
+	}
+	if !resp.Success() {
+		return resp.CodeError
+	}
+	return nil
+}
+
+// addWatcher 将用户追加为工作项关注人
+func (s *EventService) addWatcher(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, workItemTypeKey string, workItemID int64, userKey string) error {
+	info, err := s.queryWorkItem(ctx, meegoCli, config, workItemTypeKey, workItemID, []string{watchersFieldKey})
+	if err != nil {
+		return err
+	}
+	watchers := make([]string, 0, 4)
+	for _, f := range info.Fields {
+		if f.FieldKey != watchersFieldKey {
+			continue
+		}
+		if values, ok := f.FieldValue.([]interface{}); ok {
+			for _, v := range values {
+				if key, ok := v.(string); ok {
+					if key == userKey {
+						return nil
+					}
+					watchers = append(watchers, key)
+				}
+			}
+		}
+	}
+	watchers = append(watchers, userKey)
+	return s.updateWorkItemFields(ctx, meegoCli, config, workItemTypeKey, workItemID, []*field.FieldValuePair{{
+		FieldKey:   watchersFieldKey,
+		FieldValue: watchers,
+	}})
+}
+
+// createComment 在工作项下添加评论
+func (s *EventService) createComment(ctx context.Context, meegoCli *projSDK.Client, config *model.AppConfig, workItemTypeKey string, workItemID int64, content string, opUserKey string) error {
+	req := comment.NewCreateCommentReqBuilder().ProjectKey(config.ProjectKey).
+		WorkItemTypeKey(workItemTypeKey).WorkItemID(workItemID).Content(content).Build()
+	callCtx, cancel := s.callCtx(ctx, apiClassWorkItem)
+	defer cancel()
+	resp, err := meegoCli.Comment.CreateComment(callCtx, req, s.meegoUserOption(ctx, opUserKey))
+	if err != nil {
+		return err
+	}
+	if !resp.Success() {
+		return resp.CodeError
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"smart_elf_standalone/internal/model"
+	"smart_elf_standalone/pkg/config"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/larksuite/project-oapi-sdk-golang/core"
+	"github.com/larksuite/project-oapi-sdk-golang/service/field"
+	"github.com/larksuite/project-oapi-sdk-golang/service/project"
+	"github.com/larksuite/project-oapi-sdk-golang/service/workitem_conf"
+	"gorm.io/gorm"
+)
+
+// defaultMetadataTTL 未配置时元数据缓存的有效期
+const defaultMetadataTTL = 30 * time.Minute
+
+// ErrProjectForbidden 调用方无权访问或管理该空间
+var ErrProjectForbidden = errors.New("project forbidden")
+
+// errProjectNotFound 以调用方身份查询不到空间，空间不存在或调用方不是空间成员
+var errProjectNotFound = errors.New("project not found")
+
+// MetadataService 空间元数据缓存服务，缓存空间详情、工作项类型、模板与字段定义。
+// 元数据以调用方身份查询，缓存按空间与调用方的 user_key 分别保存，避免以一个用户的权限查询的数据返回给其他用户
+type MetadataService struct {
+	configService    *ConfigService
+	clientRegistry   *ClientRegistry
+	userTokenService *UserTokenService
+	ttl              time.Duration
+
+	mu    sync.RWMutex
+	cache map[string]*metadataEntry
+}
+
+// metadataEntry 缓存条目
+type metadataEntry struct {
+	value    interface{}
+	expireAt time.Time
+}
+
+// NewMetadataService 创建空间元数据缓存服务实例
+func NewMetadataService(configService *ConfigService, clientRegistry *ClientRegistry, userTokenService *UserTokenService, cfg config.MetadataConfig) *MetadataService {
+	ttl := time.Duration(cfg.TTLMinutes) * time.Minute
+	if ttl <= 0 {
+		ttl = defaultMetadataTTL
+	}
+	return &MetadataService{
+		configService:    configService,
+		clientRegistry:   clientRegistry,
+		userTokenService: userTokenService,
+		ttl:              ttl,
+		cache:            make(map[string]*metadataEntry),
+	}
+}
+
+// GetMetadata 查询空间元数据，指定工作项类型时附带模板与字段定义。
+// 以调用方身份查询，未携带调用方时使用配置中的 user_key；空间尚未保存配置时必须携带调用方
+func (s *MetadataService) GetMetadata(ctx context.Context, req *model.ProjectMetadataRequest) (*model.ProjectMetadataResponse, error) {
+	config, err := s.callerConfig(req.ProjectKey, req.UserKey)
+	if err != nil {
+		return nil, err
+	}
+	if req.Refresh {
+		s.Invalidate(req.ProjectKey)
+	}
+
+	proj, err := s.GetProject(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	types, err := s.ListWorkItemTypes(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	resp := &model.ProjectMetadataResponse{
+		ProjectKey:    config.ProjectKey,
+		Name:          proj.Name,
+		SimpleName:    proj.SimpleName,
+		WorkItemTypes: types,
+	}
+	if req.WorkItemTypeKey == "" {
+		return resp, nil
+	}
+
+	resp.WorkItemTypeKey = req.WorkItemTypeKey
+	if resp.Templates, err = s.ListTemplates(ctx, config, req.WorkItemTypeKey); err != nil {
+		return nil, err
+	}
+	if resp.Fields, err = s.ListFields(ctx, config, req.WorkItemTypeKey); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// callerConfig 构造以调用方身份查询元数据的配置，不修改已保存的配置
+func (s *MetadataService) callerConfig(projectKey, userKey string) (*model.AppConfig, error) {
+	saved, err := s.configService.GetConfigByProjectKey(projectKey)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) || userKey == "" {
+			return nil, err
+		}
+		return &model.AppConfig{ProjectKey: projectKey, APIUserKey: userKey}, nil
+	}
+	config := *saved
+	if userKey != "" {
+		config.APIUserKey = userKey
+	}
+	return &config, nil
+}
+
+// GetProject 获取空间详情
+func (s *MetadataService) GetProject(ctx context.Context, config *model.AppConfig) (*project.Project, error) {
+	value, err := s.load(metadataKey(config.ProjectKey, config.APIUserKey, "project"), func() (interface{}, error) {
+		return s.fetchProject(ctx, config)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*project.Project), nil
+}
+
+// fetchProject 不经缓存查询空间详情
+func (s *MetadataService) fetchProject(ctx context.Context, config *model.AppConfig) (*project.Project, error) {
+	meegoCli, err := s.clientRegistry.ProjectClient()
+	if err != nil {
+		return nil, err
+	}
+	callCtx, cancel := apiCallCtx(ctx, s.clientRegistry.FeishuConfig().Timeout, apiClassProject)
+	defer cancel()
+	resp, err := meegoCli.Project.GetProjectDetail(callCtx,
+		project.NewGetProjectDetailReqBuilder().ProjectKeys([]string{config.ProjectKey}).UserKey(config.APIUserKey).Build(),
+		s.meegoUserOption(ctx, config.APIUserKey))
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success() {
+		return nil, resp.CodeError
+	}
+	p, ok := resp.Data[config.ProjectKey]
+	if !ok {
+		return nil, fmt.Errorf("%w, project_key=%s", errProjectNotFound, config.ProjectKey)
+	}
+	return p, nil
+}
+
+// AuthorizeProject 校验调用方能否访问空间，manage 为 true 时要求调用方为空间管理员。
+// 以调用方身份查询空间详情，查询不到空间时视为无权访问，返回 ErrProjectForbidden；
+// 管理员身份不读缓存，避免被移除的管理员在缓存有效期内仍可管理空间
+func (s *MetadataService) AuthorizeProject(ctx context.Context, projectKey, userKey string, manage bool) error {
+	if userKey == "" {
+		return ErrProjectForbidden
+	}
+	config := &model.AppConfig{ProjectKey: projectKey, APIUserKey: userKey}
+	var (
+		proj *project.Project
+		err  error
+	)
+	if manage {
+		proj, err = s.fetchProject(ctx, config)
+	} else {
+		proj, err = s.GetProject(ctx, config)
+	}
+	if err != nil {
+		if errors.Is(err, errProjectNotFound) {
+			return ErrProjectForbidden
+		}
+		return err
+	}
+	if manage && !slices.Contains(proj.Administrators, userKey) {
+		return ErrProjectForbidden
+	}
+	return nil
+}
+
+// ListWorkItemTypes 获取空间下的工作项类型
+func (s *MetadataService) ListWorkItemTypes(ctx context.Context, config *model.AppConfig) ([]*model.WorkItemTypeMeta, error) {
+	value, err := s.load(metadataKey(config.ProjectKey, config.APIUserKey, "types"), func() (interface{}, error) {
+		meegoCli, err := s.clientRegistry.ProjectClient()
+		if err != nil {
+			return nil, err
+		}
+		callCtx, cancel := apiCallCtx(ctx, s.clientRegistry.FeishuConfig().Timeout, apiClassProject)
+		defer cancel()
+		resp, err := meegoCli.Project.ListProjectWorkItemType(callCtx,
+			project.NewListProjectWorkItemTypeReqBuilder().ProjectKey(config.ProjectKey).Build(),
+			s.meegoUserOption(ctx, config.APIUserKey))
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Success() {
+			return nil, resp.CodeError
+		}
+		types := make([]*model.WorkItemTypeMeta, 0, len(resp.Data))
+		for _, t := range resp.Data {
+			types = append(types, &model.WorkItemTypeMeta{
+				TypeKey:   t.TypeKey,
+				Name:      t.Name,
+				APIName:   t.APIName,
+				IsDisable: t.IsDisable,
+			})
+		}
+		return types, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*model.WorkItemTypeMeta), nil
+}
+
+// ListTemplates 获取工作项类型的模板列表
+func (s *MetadataService) ListTemplates(ctx context.Context, config *model.AppConfig, workItemTypeKey string) ([]*model.TemplateMeta, error) {
+	value, err := s.load(metadataKey(config.ProjectKey, config.APIUserKey, "templates", workItemTypeKey), func() (interface{}, error) {
+		meegoCli, err := s.clientRegistry.ProjectClient()
+		if err != nil {
+			return nil, err
+		}
+		callCtx, cancel := apiCallCtx(ctx, s.clientRegistry.FeishuConfig().Timeout, apiClassWorkItem)
+		defer cancel()
+		resp, err := meegoCli.WorkItemConf.QueryWorkItemTemplates(callCtx,
+			workitem_conf.NewQueryWorkItemTemplatesReqBuilder().ProjectKey(config.ProjectKey).WorkItemTypeKey(workItemTypeKey).Build(),
+			s.meegoUserOption(ctx, config.APIUserKey))
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Success() {
+			return nil, resp.CodeError
+		}
+		templates := make([]*model.TemplateMeta, 0, len(resp.Data))
+		for _, t := range resp.Data {
+			templates = append(templates, &model.TemplateMeta{
+				TemplateID:   t.TemplateID,
+				TemplateName: t.TemplateName,
+				IsDisabled:   t.IsDisabled,
+			})
+		}
+		return templates, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*model.TemplateMeta), nil
+}
+
+// ListFields 获取工作项类型的字段定义
+func (s *MetadataService) ListFields(ctx context.Context, config *model.AppConfig, workItemTypeKey string) ([]*model.FieldMeta, error) {
+	value, err := s.load(metadataKey(config.ProjectKey, config.APIUserKey, "fields", workItemTypeKey), func() (interface{}, error) {
+		meegoCli, err := s.clientRegistry.ProjectClient()
+		if err != nil {
+			return nil, err
+		}
+		callCtx, cancel := apiCallCtx(ctx, s.clientRegistry.FeishuConfig().Timeout, apiClassWorkItem)
+		defer cancel()
+		resp, err := meegoCli.Field.QueryProjectFields(callCtx,
+			field.NewQueryProjectFieldsReqBuilder().ProjectKey(config.ProjectKey).WorkItemTypeKey(workItemTypeKey).Build(),
+			s.meegoUserOption(ctx, config.APIUserKey))
+		if err != nil {
+			return nil, err
+		}
+		if !resp.Success() {
+			return nil, resp.CodeError
+		}
+		fields := make([]*model.FieldMeta, 0, len(resp.Data))
+		for _, f := range resp.Data {
+			fields = append(fields, &model.FieldMeta{
+				FieldKey:          f.FieldKey,
+				FieldAlias:        f.FieldAlias,
+				FieldName:         f.FieldName,
+				FieldTypeKey:      f.FieldTypeKey,
+				IsCustomField:     f.IsCustomField,
+				IsObsoleted:       f.IsObsoleted,
+				ValueGenerateMode: f.ValueGenerateMode,
+				Options:           convertFieldOptions(f.Options),
+			})
+		}
+		return fields, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]*model.FieldMeta), nil
+}
+
+// Invalidate 清除空间的全部元数据缓存（含所有用户）
+func (s *MetadataService) Invalidate(projectKey string) {
+	prefix := metadataKey(projectKey) + ":"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key := range s.cache {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.cache, key)
+		}
+	}
+	log.Printf("信息: 空间元数据缓存已清除: project_key=%s", projectKey)
+}
+
+// InvalidateAll 清除全部空间的元数据缓存，飞书项目地址或插件凭证变更时调用
+func (s *MetadataService) InvalidateAll() {
+	s.mu.Lock()
+	s.cache = make(map[string]*metadataEntry)
+	s.mu.Unlock()
+	log.Printf("信息: 全部空间元数据缓存已清除")
+}
+
+// load 优先读取未过期的缓存，否则调用 fetch 拉取并写入缓存
+func (s *MetadataService) load(key string, fetch func() (interface{}, error)) (interface{}, error) {
+	s.mu.RLock()
+	entry, ok := s.cache[key]
+	s.mu.RUnlock()
+	if ok && entry.expireAt.After(time.Now()) {
+		return entry.value, nil
+	}
+
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cache[key] = &metadataEntry{value: value, expireAt: time.Now().Add(s.ttl)}
+	s.mu.Unlock()
+	return value, nil
+}
+
+// meegoUserOption 以指定用户身份调用飞书项目接口，见 userRequestOption
+func (s *MetadataService) meegoUserOption(ctx context.Context, userKey string) core.RequestOptionFunc {
+	return userRequestOption(ctx, s.userTokenService, userKey)
+}
+
+// metadataKey 拼接缓存键，依次为空间、user_key 与元数据类型
+func metadataKey(parts ...string) string {
+	return strings.Join(parts, ":")
+}
+
+// convertFieldOptions 转换字段选项
+func convertFieldOptions(options []*field.Option) []*model.FieldOptionMeta {
+	if len(options) == 0 {
+		return nil
+	}
+	result := make([]*model.FieldOptionMeta, 0, len(options))
+	for _, o := range options {
+		result = append(result, &model.FieldOptionMeta{
+			Label:    o.Label,
+			Value:    o.Value,
+			Children: convertFieldOptions(o.Children),
+		})
+	}
+	return result
+}
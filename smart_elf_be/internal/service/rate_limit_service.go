@@ -0,0 +1,140 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"smart_elf_standalone/internal/model"
+	"smart_elf_standalone/pkg/config"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 限流维度
+const (
+	RateLimitScopeSender  = "sender"
+	RateLimitScopeChat    = "chat"
+	RateLimitScopeProject = "project"
+)
+
+// RateLimitService 工单创建与聊天指令限流服务，令牌桶状态保存在数据库中以便重启后依然生效
+type RateLimitService struct {
+	db  *gorm.DB
+	cfg config.RateLimitConfig
+}
+
+// NewRateLimitService 创建限流服务实例
+func NewRateLimitService(db *gorm.DB, cfg config.RateLimitConfig) *RateLimitService {
+	return &RateLimitService{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// rateLimitMaxAttempts 限流检查事务的最大尝试次数
+const rateLimitMaxAttempts = 3
+
+// rateLimitTarget 一次限流检查涉及的令牌桶
+type rateLimitTarget struct {
+	scope string
+	key   string
+	rule  config.RateLimitRule
+}
+
+// Allow 检查并消耗发送者、群聊与项目三个维度的令牌，返回是否放行以及被限流的维度
+func (s *RateLimitService) Allow(projectKey, chatID, senderID string) (bool, string, error) {
+	targets := make([]rateLimitTarget, 0, 3)
+	if senderID != "" {
+		targets = append(targets, rateLimitTarget{RateLimitScopeSender, fmt.Sprintf("%s:%s:%s", RateLimitScopeSender, projectKey, senderID), s.cfg.PerSender})
+	}
+	if chatID != "" {
+		targets = append(targets, rateLimitTarget{RateLimitScopeChat, fmt.Sprintf("%s:%s:%s", RateLimitScopeChat, projectKey, chatID), s.cfg.PerChat})
+	}
+	targets = append(targets, rateLimitTarget{RateLimitScopeProject, fmt.Sprintf("%s:%s", RateLimitScopeProject, projectKey), s.cfg.PerProject})
+
+	var (
+		allowed      bool
+		limitedScope string
+		err          error
+	)
+	// 并发请求同时创建同一令牌桶时后提交的一方会违反唯一约束，重试后按已存在的令牌桶扣减
+	for attempt := 1; attempt <= rateLimitMaxAttempts; attempt++ {
+		allowed, limitedScope, err = s.consume(targets, time.Now())
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		log.Printf("错误: 限流检查失败: %v", err)
+		return false, "", err
+	}
+	if !allowed {
+		log.Printf("警告: 消息被限流: project_key=%s, scope=%s, chat_id=%s, sender=%s", projectKey, limitedScope, chatID, senderID)
+	}
+	return allowed, limitedScope, nil
+}
+
+// consume 在一个事务内读取并扣减各维度令牌，所有维度均有余量时才放行
+func (s *RateLimitService) consume(targets []rateLimitTarget, now time.Time) (bool, string, error) {
+	allowed := true
+	limitedScope := ""
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		buckets := make([]*model.RateLimitBucket, 0, len(targets))
+		for _, t := range targets {
+			if t.rule.Capacity <= 0 {
+				buckets = append(buckets, nil)
+				continue
+			}
+			bucket, err := s.loadBucket(tx, t.key, t.rule, now)
+			if err != nil {
+				return err
+			}
+			buckets = append(buckets, bucket)
+			if allowed && bucket.Tokens < 1 {
+				allowed = false
+				limitedScope = t.scope
+			}
+		}
+
+		// 所有维度均有余量时才统一扣减，避免部分扣减
+		for _, bucket := range buckets {
+			if bucket == nil {
+				continue
+			}
+			if allowed {
+				bucket.Tokens--
+			}
+			if err := tx.Save(bucket).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return allowed, limitedScope, err
+}
+
+// loadBucket 加锁读取令牌桶并按时间补充令牌，不存在时返回满桶
+func (s *RateLimitService) loadBucket(tx *gorm.DB, key string, rule config.RateLimitRule, now time.Time) (*model.RateLimitBucket, error) {
+	var bucket model.RateLimitBucket
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("bucket_key = ?", key).First(&bucket).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &model.RateLimitBucket{
+				BucketKey:  key,
+				Tokens:     float64(rule.Capacity),
+				RefilledAt: now,
+			}, nil
+		}
+		return nil, err
+	}
+
+	elapsed := now.Sub(bucket.RefilledAt).Minutes()
+	if elapsed > 0 {
+		bucket.Tokens = math.Min(float64(rule.Capacity), bucket.Tokens+elapsed*rule.RefillPerMinute)
+		bucket.RefilledAt = now
+	}
+	return &bucket, nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"smart_elf_standalone/internal/model"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// defaultStatsDays 未指定时间范围时默认统计的天数
+const defaultStatsDays = 30
+
+// StatsService 工单统计服务，基于本地工单台账聚合
+type StatsService struct {
+	db *gorm.DB
+}
+
+// NewStatsService 创建工单统计服务实例
+func NewStatsService(db *gorm.DB) *StatsService {
+	return &StatsService{
+		db: db,
+	}
+}
+
+// GetTicketStats 按时间、群聊、提单人与工作项类型统计已创建的工单，按状态统计全部建单尝试
+func (s *StatsService) GetTicketStats(req *model.TicketStatsRequest) (*model.TicketStatsResponse, error) {
+	to := time.Now()
+	if req.To > 0 {
+		to = time.Unix(req.To, 0)
+	}
+	from := to.AddDate(0, 0, -defaultStatsDays)
+	if req.From > 0 {
+		from = time.Unix(req.From, 0)
+	}
+	period := req.Period
+	if period != model.StatsPeriodWeek {
+		period = model.StatsPeriodDay
+	}
+
+	var tickets []*model.Ticket
+	err := s.db.Select("id", "created_at", "work_item_type_key", "source_chat_id", "reporter_open_id",
+		"reporter_name", "status", "first_response_at", "closed_at").
+		Where("project_key = ? AND created_at >= ? AND created_at < ?", req.ProjectKey, from, to).
+		Find(&tickets).Error
+	if err != nil {
+		log.Printf("错误: 查询工单统计数据失败: %v", err)
+		return nil, err
+	}
+
+	byStatus := newStatsCounter()
+	byPeriod := newStatsCounter()
+	byChat := newStatsCounter()
+	byReporter := newStatsCounter()
+	byType := newStatsCounter()
+	firstResponse := make([]float64, 0, len(tickets))
+	closeDurations := make([]float64, 0, len(tickets))
+	var total int64
+	for _, t := range tickets {
+		// 按状态统计全部建单尝试，其余维度只统计已成功创建的工单
+		byStatus.add(t.Status, "")
+		if t.Status != model.TicketStatusCreated {
+			continue
+		}
+		total++
+		byPeriod.add(periodKey(t.CreatedAt, period), "")
+		byChat.add(t.SourceChatID, "")
+		byReporter.add(t.ReporterOpenID, t.ReporterName)
+		byType.add(t.WorkItemTypeKey, "")
+		if t.FirstResponseAt != nil {
+			firstResponse = append(firstResponse, t.FirstResponseAt.Sub(t.CreatedAt).Seconds())
+		}
+		if t.ClosedAt != nil {
+			closeDurations = append(closeDurations, t.ClosedAt.Sub(t.CreatedAt).Seconds())
+		}
+	}
+
+	return &model.TicketStatsResponse{
+		ProjectKey:     req.ProjectKey,
+		From:           from.Unix(),
+		To:             to.Unix(),
+		Period:         period,
+		Total:          total,
+		ByStatus:       byStatus.buckets(false),
+		ByPeriod:       byPeriod.buckets(true),
+		ByChat:         byChat.buckets(false),
+		ByReporter:     byReporter.buckets(false),
+		ByWorkItemType: byType.buckets(false),
+		FirstResponse:  durationStats(firstResponse),
+		Close:          durationStats(closeDurations),
+	}, nil
+}
+
+// TicketStatsCSV 将统计结果导出为 CSV，每行为 dimension,key,label,value
+func (s *StatsService) TicketStatsCSV(stats *model.TicketStatsResponse) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"dimension", "key", "label", "value"}}
+	rows = append(rows, []string{"total", "", "", strconv.FormatInt(stats.Total, 10)})
+	appendBuckets := func(dimension string, buckets []*model.StatsBucket) {
+		for _, b := range buckets {
+			rows = append(rows, []string{dimension, b.Key, b.Label, strconv.FormatInt(b.Count, 10)})
+		}
+	}
+	appendBuckets("status", stats.ByStatus)
+	appendBuckets(stats.Period, stats.ByPeriod)
+	appendBuckets("chat", stats.ByChat)
+	appendBuckets("reporter", stats.ByReporter)
+	appendBuckets("work_item_type", stats.ByWorkItemType)
+	appendDuration := func(dimension string, d *model.DurationStats) {
+		rows = append(rows,
+			[]string{dimension, "count", "", strconv.FormatInt(d.Count, 10)},
+			[]string{dimension, "avg_seconds", "", fmt.Sprintf("%.0f", d.AvgSeconds)},
+			[]string{dimension, "median_seconds", "", fmt.Sprintf("%.0f", d.MedianSeconds)},
+			[]string{dimension, "p90_seconds", "", fmt.Sprintf("%.0f", d.P90Seconds)},
+		)
+	}
+	appendDuration("time_to_first_response", stats.FirstResponse)
+	appendDuration("time_to_close", stats.Close)
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// statsCounter 按维度值计数
+type statsCounter struct {
+	counts map[string]int64
+	labels map[string]string
+}
+
+func newStatsCounter() *statsCounter {
+	return &statsCounter{
+		counts: make(map[string]int64),
+		labels: make(map[string]string),
+	}
+}
+
+func (c *statsCounter) add(key, label string) {
+	c.counts[key]++
+	if label != "" {
+		c.labels[key] = label
+	}
+}
+
+// buckets 输出计数结果，byKey 为 true 时按维度值升序，否则按数量降序
+func (c *statsCounter) buckets(byKey bool) []*model.StatsBucket {
+	result := make([]*model.StatsBucket, 0, len(c.counts))
+	for key, count := range c.counts {
+		result = append(result, &model.StatsBucket{Key: key, Label: c.labels[key], Count: count})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if byKey || result[i].Count == result[j].Count {
+			return result[i].Key < result[j].Key
+		}
+		return result[i].Count > result[j].Count
+	})
+	return result
+}
+
+// periodKey 返回时间所属的日期（day）或该周周一的日期（week）
+func periodKey(t time.Time, period string) string {
+	if period == model.StatsPeriodWeek {
+		offset := (int(t.Weekday()) + 6) % 7
+		t = t.AddDate(0, 0, -offset)
+	}
+	return t.Format("2006-01-02")
+}
+
+// durationStats 计算耗时的平均值、中位数与P90
+func durationStats(values []float64) *model.DurationStats {
+	result := &model.DurationStats{Count: int64(len(values))}
+	if len(values) == 0 {
+		return result
+	}
+	sort.Float64s(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	result.AvgSeconds = sum / float64(len(values))
+	result.MedianSeconds = percentile(values, 0.5)
+	result.P90Seconds = percentile(values, 0.9)
+	return result
+}
+
+// percentile 计算已排序数据的分位数（最近秩法）
+func percentile(sorted []float64, p float64) float64 {
+	idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx]
+}
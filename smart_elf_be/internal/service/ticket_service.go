@@ -0,0 +1,176 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"smart_elf_standalone/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 工单台账分页参数
+const (
+	defaultTicketPageSize = 20
+	maxTicketPageSize     = 100
+)
+
+// TicketService 工单台账服务
+type TicketService struct {
+	db *gorm.DB
+}
+
+// NewTicketService 创建工单台账服务实例
+func NewTicketService(db *gorm.DB) *TicketService {
+	return &TicketService{
+		db: db,
+	}
+}
+
+// CreatePending 在调用飞书项目前记录待创建的工单
+func (s *TicketService) CreatePending(ticket *model.Ticket) error {
+	ticket.Status = model.TicketStatusPending
+	if err := s.db.Create(ticket).Error; err != nil {
+		log.Printf("错误: 记录工单台账失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// MarkCreated 标记工单创建成功
+func (s *TicketService) MarkCreated(id uint, workItemID int64) error {
+	return s.updateStatus(id, map[string]interface{}{
+		"status":       model.TicketStatusCreated,
+		"work_item_id": workItemID,
+		"err_msg":      "",
+	})
+}
+
+// MarkMerged 标记工单已合并到已有工作项
+func (s *TicketService) MarkMerged(id uint, workItemID int64) error {
+	return s.updateStatus(id, map[string]interface{}{
+		"status":       model.TicketStatusMerged,
+		"work_item_id": workItemID,
+	})
+}
+
+// MarkFailed 标记工单创建失败并记录原因
+func (s *TicketService) MarkFailed(id uint, cause error) error {
+	return s.updateStatus(id, map[string]interface{}{
+		"status":  model.TicketStatusFailed,
+		"err_msg": cause.Error(),
+	})
+}
+
+// SetGroupChat 记录工单关联的群聊
+func (s *TicketService) SetGroupChat(projectKey string, workItemID int64, chatID string) error {
+	err := s.db.Model(&model.Ticket{}).Where("project_key = ? AND work_item_id = ?", projectKey, workItemID).
+		Updates(map[string]interface{}{
+			"group_chat_id": chatID,
+			"updated_at":    time.Now(),
+		}).Error
+	if err != nil {
+		log.Printf("错误: 更新工单群聊失败: %v", err)
+	}
+	return err
+}
+
+// updateStatus 更新工单台账记录
+func (s *TicketService) updateStatus(id uint, updates map[string]interface{}) error {
+	if id == 0 {
+		return errors.New("invalid ticket id")
+	}
+	updates["updated_at"] = time.Now()
+	if err := s.db.Model(&model.Ticket{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		log.Printf("错误: 更新工单台账失败: %v, id=%d", err, id)
+		return err
+	}
+	return nil
+}
+
+// GetTicket 查询空间下的单条工单台账，不属于该空间的工单视为不存在
+func (s *TicketService) GetTicket(projectKey string, id uint) (*model.Ticket, error) {
+	var ticket model.Ticket
+	if err := s.db.Where("project_key = ?", projectKey).First(&ticket, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ticket not found")
+		}
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// ListTickets 按条件分页查询工单台账
+func (s *TicketService) ListTickets(req *model.TicketListRequest) (*model.TicketListResponse, error) {
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultTicketPageSize
+	}
+	if pageSize > maxTicketPageSize {
+		pageSize = maxTicketPageSize
+	}
+
+	query := s.db.Model(&model.Ticket{}).Where("project_key = ?", req.ProjectKey)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.ReporterOpenID != "" {
+		query = query.Where("reporter_open_id = ?", req.ReporterOpenID)
+	}
+	if req.SourceChatID != "" {
+		query = query.Where("source_chat_id = ?", req.SourceChatID)
+	}
+	if req.WorkItemID != 0 {
+		query = query.Where("work_item_id = ?", req.WorkItemID)
+	}
+	if req.CreatedFrom > 0 {
+		query = query.Where("created_at >= ?", time.Unix(req.CreatedFrom, 0))
+	}
+	if req.CreatedTo > 0 {
+		query = query.Where("created_at < ?", time.Unix(req.CreatedTo, 0))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("错误: 统计工单台账失败: %v", err)
+		return nil, err
+	}
+	items := make([]*model.Ticket, 0, pageSize)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		log.Printf("错误: 查询工单台账失败: %v", err)
+		return nil, err
+	}
+
+	return &model.TicketListResponse{
+		Items:    items,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// ListUnclosedTickets 按 id 升序分页查询已创建工作项但尚未同步到关闭时间的工单，afterID 为上一页最后一条的 id
+func (s *TicketService) ListUnclosedTickets(projectKey string, afterID uint, limit int) ([]*model.Ticket, error) {
+	var tickets []*model.Ticket
+	err := s.db.Where("project_key = ? AND status = ? AND work_item_id > 0 AND closed_at IS NULL AND id > ?",
+		projectKey, model.TicketStatusCreated, afterID).Order("id ASC").Limit(limit).Find(&tickets).Error
+	return tickets, err
+}
+
+// UpdateStateTimes 记录工单的首次响应与关闭时间
+func (s *TicketService) UpdateStateTimes(id uint, firstResponseAt, closedAt *time.Time) error {
+	updates := map[string]interface{}{}
+	if firstResponseAt != nil {
+		updates["first_response_at"] = *firstResponseAt
+	}
+	if closedAt != nil {
+		updates["closed_at"] = *closedAt
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return s.updateStatus(id, updates)
+}
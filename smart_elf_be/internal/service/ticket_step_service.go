@@ -0,0 +1,245 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"smart_elf_standalone/internal/model"
+	"smart_elf_standalone/pkg/config"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 后续步骤重试默认参数
+const (
+	defaultStepMaxAttempts       = 5
+	defaultStepBackoffSeconds    = 30
+	defaultStepMaxBackoffSeconds = 1800
+	stepRetryBatchSize           = 100
+)
+
+// TicketStepService 工单后续步骤服务，负责步骤状态的持久化与退避计算
+type TicketStepService struct {
+	db  *gorm.DB
+	cfg config.StepConfig
+}
+
+// NewTicketStepService 创建工单后续步骤服务实例
+func NewTicketStepService(db *gorm.DB, cfg config.StepConfig) *TicketStepService {
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultStepMaxAttempts
+	}
+	if cfg.BackoffSeconds <= 0 {
+		cfg.BackoffSeconds = defaultStepBackoffSeconds
+	}
+	if cfg.MaxBackoffSeconds <= 0 {
+		cfg.MaxBackoffSeconds = defaultStepMaxBackoffSeconds
+	}
+	return &TicketStepService{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+// RetryInterval 扫描待重试步骤的间隔，为 0 时不启动定时重试
+func (s *TicketStepService) RetryInterval() time.Duration {
+	return time.Duration(s.cfg.RetryIntervalSeconds) * time.Second
+}
+
+// CreateStep 记录待执行的步骤
+func (s *TicketStepService) CreateStep(step *model.TicketStep) error {
+	step.Status = model.TicketStepStatusPending
+	if err := s.db.Create(step).Error; err != nil {
+		log.Printf("错误: 记录工单后续步骤失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// Claim 将步骤置为执行中，返回 false 表示步骤已被其他协程领取或无需执行
+func (s *TicketStepService) Claim(step *model.TicketStep) (bool, error) {
+	result := s.db.Model(&model.TicketStep{}).
+		Where("id = ? AND status IN ?", step.ID, []string{model.TicketStepStatusPending, model.TicketStepStatusRetrying}).
+		Updates(map[string]interface{}{
+			"status":     model.TicketStepStatusRunning,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	step.Status = model.TicketStepStatusRunning
+	step.Attempts++
+	return true, nil
+}
+
+// MarkSucceeded 标记步骤执行成功
+func (s *TicketStepService) MarkSucceeded(step *model.TicketStep, result string) error {
+	step.Status = model.TicketStepStatusSucceeded
+	step.Result = result
+	step.NextRetryAt = nil
+	step.ErrMsg = ""
+	return s.update(step.ID, map[string]interface{}{
+		"status":        step.Status,
+		"result":        result,
+		"next_retry_at": nil,
+		"err_msg":       "",
+	})
+}
+
+// MarkFailed 记录步骤失败原因，未超过最大尝试次数时按指数退避安排重试。
+// 未开启定时重试时没有协程执行重试，直接标记为失败等待重放
+func (s *TicketStepService) MarkFailed(step *model.TicketStep, cause error) error {
+	step.ErrMsg = cause.Error()
+	if step.Attempts >= s.cfg.MaxAttempts || s.cfg.RetryIntervalSeconds <= 0 {
+		step.Status = model.TicketStepStatusFailed
+		step.NextRetryAt = nil
+	} else {
+		next := time.Now().Add(s.backoff(step.Attempts))
+		step.Status = model.TicketStepStatusRetrying
+		step.NextRetryAt = &next
+	}
+	return s.update(step.ID, map[string]interface{}{
+		"status":        step.Status,
+		"next_retry_at": step.NextRetryAt,
+		"err_msg":       step.ErrMsg,
+	})
+}
+
+// RequeueUnfinishedSteps 将上次停机前未执行或未执行完的步骤放回重试队列
+func (s *TicketStepService) RequeueUnfinishedSteps() error {
+	now := time.Now()
+	return s.db.Model(&model.TicketStep{}).
+		Where("status IN ?", []string{model.TicketStepStatusPending, model.TicketStepStatusRunning}).
+		Updates(map[string]interface{}{
+			"status":        model.TicketStepStatusRetrying,
+			"next_retry_at": now,
+			"updated_at":    now,
+		}).Error
+}
+
+// Release 将被中断的步骤放回重试队列，本次尝试不计入次数
+func (s *TicketStepService) Release(step *model.TicketStep) error {
+	now := time.Now()
+	step.Status = model.TicketStepStatusRetrying
+	step.Attempts--
+	step.NextRetryAt = &now
+	return s.update(step.ID, map[string]interface{}{
+		"status":        step.Status,
+		"attempts":      step.Attempts,
+		"next_retry_at": now,
+	})
+}
+
+// ListDueSteps 查询已到重试时间的步骤
+func (s *TicketStepService) ListDueSteps() ([]*model.TicketStep, error) {
+	var steps []*model.TicketStep
+	err := s.db.Where("status = ? AND next_retry_at <= ?", model.TicketStepStatusRetrying, time.Now()).
+		Order("next_retry_at ASC").Limit(stepRetryBatchSize).Find(&steps).Error
+	return steps, err
+}
+
+// ResetFailedSteps 将失败或等待重试的步骤重置为待执行并清零尝试次数，stepIDs 为空时重置项目下全部此类步骤。
+// 包含等待重试的步骤，以便关闭定时重试前遗留的步骤也能重放
+func (s *TicketStepService) ResetFailedSteps(projectKey string, stepIDs []uint) ([]*model.TicketStep, error) {
+	var steps []*model.TicketStep
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("project_key = ? AND status IN ?", projectKey,
+			[]string{model.TicketStepStatusFailed, model.TicketStepStatusRetrying})
+		if len(stepIDs) > 0 {
+			query = query.Where("id IN ?", stepIDs)
+		}
+		if err := query.Find(&steps).Error; err != nil {
+			return err
+		}
+		for _, step := range steps {
+			step.Status = model.TicketStepStatusPending
+			step.Attempts = 0
+			step.NextRetryAt = nil
+			err := tx.Model(&model.TicketStep{}).Where("id = ?", step.ID).Updates(map[string]interface{}{
+				"status":        step.Status,
+				"attempts":      0,
+				"next_retry_at": nil,
+				"updated_at":    time.Now(),
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("错误: 重置失败步骤失败: %v, project_key=%s", err, projectKey)
+		return nil, err
+	}
+	return steps, nil
+}
+
+// ListSteps 按条件分页查询后续步骤
+func (s *TicketStepService) ListSteps(req *model.TicketStepListRequest) (*model.TicketStepListResponse, error) {
+	page, pageSize := req.Page, req.PageSize
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultTicketPageSize
+	}
+	if pageSize > maxTicketPageSize {
+		pageSize = maxTicketPageSize
+	}
+
+	query := s.db.Model(&model.TicketStep{}).Where("project_key = ?", req.ProjectKey)
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.WorkItemID != 0 {
+		query = query.Where("work_item_id = ?", req.WorkItemID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		log.Printf("错误: 统计后续步骤失败: %v", err)
+		return nil, err
+	}
+	items := make([]*model.TicketStep, 0, pageSize)
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		log.Printf("错误: 查询后续步骤失败: %v", err)
+		return nil, err
+	}
+
+	return &model.TicketStepListResponse{
+		Items:    items,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// update 更新步骤记录
+func (s *TicketStepService) update(id uint, updates map[string]interface{}) error {
+	if id == 0 {
+		return errors.New("invalid ticket step id")
+	}
+	updates["updated_at"] = time.Now()
+	if err := s.db.Model(&model.TicketStep{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+		log.Printf("错误: 更新后续步骤失败: %v, id=%d", err, id)
+		return err
+	}
+	return nil
+}
+
+// backoff 第 attempts 次失败后的退避时间
+func (s *TicketStepService) backoff(attempts int) time.Duration {
+	d := time.Duration(s.cfg.BackoffSeconds) * time.Second
+	max := time.Duration(s.cfg.MaxBackoffSeconds) * time.Second
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
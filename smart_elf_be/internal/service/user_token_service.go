@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"smart_elf_standalone/internal/auth"
+	"smart_elf_standalone/internal/model"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// userTokenRefreshBefore 用户令牌距过期多久时提前刷新
+const userTokenRefreshBefore = 5 * time.Minute
+
+// userUnauthorizedCacheTTL 缓存用户未授权结果的时长，避免每次调用飞书项目接口都查询数据库
+const userUnauthorizedCacheTTL = time.Minute
+
+// ErrUserNotAuthorized 用户未授权或授权已失效
+var ErrUserNotAuthorized = errors.New("user not authorized")
+
+// UserTokenService 飞书项目用户授权凭证服务，按用户持久化凭证并在临近过期时刷新
+type UserTokenService struct {
+	db         *gorm.DB
+	feishuAuth *auth.FeishuAuth
+	// locks 按 user_key 串行化刷新，避免同一用户的刷新令牌被并发使用
+	locks sync.Map
+	// cache 按 user_key 缓存有效的访问令牌或未授权结果
+	cache sync.Map
+}
+
+// cachedUserToken 缓存的用户令牌，token 为空表示用户未授权
+type cachedUserToken struct {
+	token    string
+	expireAt time.Time
+}
+
+// NewUserTokenService 创建用户授权凭证服务实例
+func NewUserTokenService(db *gorm.DB, feishuAuth *auth.FeishuAuth) *UserTokenService {
+	return &UserTokenService{
+		db:         db,
+		feishuAuth: feishuAuth,
+	}
+}
+
+// Authorize 使用插件授权码换取并保存用户凭证
+func (s *UserTokenService) Authorize(ctx context.Context, code string) (*auth.UserToken, error) {
+	token, err := s.feishuAuth.ExchangeCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.save(token); err != nil {
+		return nil, err
+	}
+	log.Printf("信息: 用户授权凭证已保存: user_key=%s", token.UserKey)
+	return token, nil
+}
+
+// AccessToken 获取用户的访问令牌，临近过期时刷新；用户未授权或授权失效时返回 ErrUserNotAuthorized
+func (s *UserTokenService) AccessToken(ctx context.Context, userKey string) (string, error) {
+	if userKey == "" {
+		return "", ErrUserNotAuthorized
+	}
+	if v, ok := s.cache.Load(userKey); ok {
+		cached := v.(*cachedUserToken)
+		if cached.token == "" && time.Now().Before(cached.expireAt) {
+			return "", ErrUserNotAuthorized
+		}
+		if cached.token != "" && time.Until(cached.expireAt) > userTokenRefreshBefore {
+			return cached.token, nil
+		}
+	}
+	record, err := s.load(userKey)
+	if err != nil {
+		return "", err
+	}
+	if time.Until(record.ExpireAt) > userTokenRefreshBefore {
+		s.cache.Store(userKey, &cachedUserToken{token: record.AccessToken, expireAt: record.ExpireAt})
+		return record.AccessToken, nil
+	}
+
+	mu, _ := s.locks.LoadOrStore(userKey, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+
+	// 等待锁期间可能已被其他协程刷新
+	if record, err = s.load(userKey); err != nil {
+		return "", err
+	}
+	if time.Until(record.ExpireAt) > userTokenRefreshBefore {
+		return record.AccessToken, nil
+	}
+	if time.Now().After(record.RefreshExpireAt) {
+		s.revoke(userKey)
+		return "", ErrUserNotAuthorized
+	}
+
+	token, err := s.feishuAuth.RefreshUserToken(ctx, userKey, record.RefreshToken)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) {
+			s.revoke(userKey)
+			return "", ErrUserNotAuthorized
+		}
+		// 网络等临时错误时，令牌未过期仍可继续使用
+		if time.Now().Before(record.ExpireAt) {
+			log.Printf("警告: 刷新用户令牌失败，继续使用未过期的令牌: %v, user_key=%s", err, userKey)
+			return record.AccessToken, nil
+		}
+		return "", err
+	}
+	if err := s.save(token); err != nil {
+		return "", err
+	}
+	return token.Token, nil
+}
+
+// load 查询用户凭证
+func (s *UserTokenService) load(userKey string) (*model.UserToken, error) {
+	// 未授权是常态，使用 Find 避免 First 在记录不存在时输出日志
+	var records []*model.UserToken
+	if err := s.db.Where("user_key = ?", userKey).Limit(1).Find(&records).Error; err != nil {
+		log.Printf("错误: 查询用户授权凭证失败: %v", err)
+		return nil, err
+	}
+	if len(records) == 0 {
+		s.cache.Store(userKey, &cachedUserToken{expireAt: time.Now().Add(userUnauthorizedCacheTTL)})
+		return nil, ErrUserNotAuthorized
+	}
+	return records[0], nil
+}
+
+// save 按 user_key 写入或更新用户凭证
+func (s *UserTokenService) save(token *auth.UserToken) error {
+	record := &model.UserToken{
+		UserKey:         token.UserKey,
+		AccessToken:     token.Token,
+		ExpireAt:        token.ExpireAt,
+		RefreshToken:    token.RefreshToken,
+		RefreshExpireAt: token.RefreshExpireAt,
+	}
+	err := s.db.Unscoped().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"access_token":      record.AccessToken,
+			"expire_at":         record.ExpireAt,
+			"refresh_token":     record.RefreshToken,
+			"refresh_expire_at": record.RefreshExpireAt,
+			"updated_at":        time.Now(),
+			"deleted_at":        nil,
+		}),
+	}).Create(record).Error
+	if err != nil {
+		log.Printf("错误: 保存用户授权凭证失败: %v", err)
+		return err
+	}
+	s.cache.Store(token.UserKey, &cachedUserToken{token: token.Token, expireAt: token.ExpireAt})
+	return nil
+}
+
+// revoke 删除已失效的用户凭证
+func (s *UserTokenService) revoke(userKey string) {
+	s.cache.Store(userKey, &cachedUserToken{expireAt: time.Now().Add(userUnauthorizedCacheTTL)})
+	if err := s.db.Where("user_key = ?", userKey).Delete(&model.UserToken{}).Error; err != nil {
+		log.Printf("错误: 删除用户授权凭证失败: %v", err)
+		return
+	}
+	log.Printf("信息: 用户授权已失效: user_key=%s", userKey)
+}
@@ -1,21 +1,30 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config 应用配置
 type Config struct {
-	Server   ServerConfig   `yaml:"server"`
-	Database DatabaseConfig `yaml:"database"`
-	Feishu   FeishuConfig   `yaml:"feishu"`
-	Logger   LoggerConfig   `yaml:"logger"`
+	Server    ServerConfig    `yaml:"server"`
+	Database  DatabaseConfig  `yaml:"database"`
+	Feishu    FeishuConfig    `yaml:"feishu"`
+	Logger    LoggerConfig    `yaml:"logger"`
+	RateLimit RateLimitConfig `yaml:"rate_limit"`
+	Duplicate DuplicateConfig `yaml:"duplicate"`
+	Group     GroupConfig     `yaml:"group"`
+	Step      StepConfig      `yaml:"step"`
+	Metadata  MetadataConfig  `yaml:"metadata"`
+	Proxy     ProxyConfig     `yaml:"proxy"`
 }
 
 type FeishuConfig struct {
@@ -26,20 +35,138 @@ type FeishuConfig struct {
 	PluginSecret   string `yaml:"plugin_secret"`
 	ProjectAPIHost string `yaml:"project_api_host"`
 	ProjectWebHost string `yaml:"project_web_host"`
+	// 插件令牌配置
+	PluginToken PluginTokenConfig `yaml:"plugin_token"`
+	// 外部接口调用超时配置
+	Timeout APITimeoutConfig `yaml:"timeout"`
+}
+
+// PluginTokenConfig 飞书项目插件令牌配置
+type PluginTokenConfig struct {
+	// 令牌类型：plugin（插件令牌）或 virtual_plugin（虚拟插件令牌，仅用于开发调试），为空时使用 plugin
+	Type string `yaml:"type"`
+	// 距过期多少秒时提前刷新，为 0 时使用默认值
+	RefreshBeforeSeconds int `yaml:"refresh_before_seconds"`
+	// 提前刷新时间的随机抖动（秒），避免多实例同时刷新，为 0 时使用默认值
+	RefreshJitterSeconds int `yaml:"refresh_jitter_seconds"`
+}
+
+// APITimeoutConfig 各类外部接口的调用超时时间（秒），为 0 时使用默认值
+type APITimeoutConfig struct {
+	ContactSeconds  int `yaml:"contact_seconds"`
+	IMSeconds       int `yaml:"im_seconds"`
+	ProjectSeconds  int `yaml:"project_seconds"`
+	WorkItemSeconds int `yaml:"work_item_seconds"`
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	Port int    `yaml:"port"`
 	Host string `yaml:"host"`
+	// 停机时等待进行中的请求完成的超时时间（秒）
+	ShutdownTimeoutSeconds int `yaml:"shutdown_timeout_seconds"`
+	// 停机时等待事件后台任务完成的超时时间（秒），与请求停机分别计时
+	DrainTimeoutSeconds int `yaml:"drain_timeout_seconds"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	// DSN 连接串，按协议选择驱动：sqlite://<路径>、mysql://、postgres://
 	DSN          string `yaml:"dsn"`
 	MaxIdleConns int    `yaml:"max_idle_conns"`
 	MaxOpenConns int    `yaml:"max_open_conns"`
-	Debug        bool   `yaml:"debug"`
+	// ConnMaxLifetimeSeconds 连接最长存活时间，0 表示不限制
+	ConnMaxLifetimeSeconds int `yaml:"conn_max_lifetime_seconds"`
+	// ConnMaxIdleTimeSeconds 连接最长空闲时间，0 表示不限制
+	ConnMaxIdleTimeSeconds int  `yaml:"conn_max_idle_time_seconds"`
+	Debug                  bool `yaml:"debug"`
+}
+
+// RateLimitConfig 工单创建与聊天指令限流配置
+type RateLimitConfig struct {
+	PerSender  RateLimitRule `yaml:"per_sender"`
+	PerChat    RateLimitRule `yaml:"per_chat"`
+	PerProject RateLimitRule `yaml:"per_project"`
+}
+
+// RateLimitRule 令牌桶规则，Capacity为0时不限流
+type RateLimitRule struct {
+	Capacity        int     `yaml:"capacity"`
+	RefillPerMinute float64 `yaml:"refill_per_minute"`
+}
+
+// DuplicateConfig 重复工单检测配置
+type DuplicateConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// 标题相似度阈值，取值 0~1
+	Threshold float64 `yaml:"threshold"`
+	// 仅与该时间窗口内创建的工单比较
+	WindowMinutes int `yaml:"window_minutes"`
+	// 命中重复时的处理方式: watcher 将提单人加为关注人, comment 在已有工单下评论
+	Action string `yaml:"action"`
+}
+
+// GroupConfig 工单群生命周期配置
+type GroupConfig struct {
+	// 定时对账间隔（分钟），为 0 时仅依赖飞书项目事件，已解决的工单群仍按 close_delay_minutes 定时归档
+	ReconcileIntervalMinutes int `yaml:"reconcile_interval_minutes"`
+	// 工单关闭后的处理方式: dissolve 解散群, leave 机器人退群
+	CloseAction string `yaml:"close_action"`
+	// 工单关闭后延迟多久执行 close_action（分钟）
+	CloseDelayMinutes int `yaml:"close_delay_minutes"`
+	// 工单关闭后群名称前缀
+	ResolvedPrefix string `yaml:"resolved_prefix"`
+}
+
+// StepConfig 工单创建后续步骤的重试配置
+type StepConfig struct {
+	// 最大尝试次数，超过后标记为失败，需人工重放
+	MaxAttempts int `yaml:"max_attempts"`
+	// 首次重试的退避时间（秒），之后每次翻倍
+	BackoffSeconds int `yaml:"backoff_seconds"`
+	// 退避时间上限（秒）
+	MaxBackoffSeconds int `yaml:"max_backoff_seconds"`
+	// 扫描待重试步骤的间隔（秒），为 0 时不定时重试，失败的步骤直接标记为失败，需通过接口重放
+	RetryIntervalSeconds int `yaml:"retry_interval_seconds"`
+}
+
+// MetadataConfig 空间元数据缓存配置
+type MetadataConfig struct {
+	// 缓存有效期（分钟）
+	TTLMinutes int `yaml:"ttl_minutes"`
+}
+
+// ProxyConfig 飞书项目 OpenAPI 代理配置
+type ProxyConfig struct {
+	// 插件会话签名密钥，为空时启动时随机生成（重启后会话失效）
+	SessionSecret string `yaml:"session_secret"`
+	// 插件会话有效期（分钟）
+	SessionTTLMinutes int `yaml:"session_ttl_minutes"`
+	// 允许代理的接口，未命中的请求一律拒绝
+	Allow []ProxyRule `yaml:"allow"`
+	// 转发到上游的连接配置
+	Transport ProxyTransportConfig `yaml:"transport"`
+}
+
+// ProxyTransportConfig 代理上游连接配置，时间单位为秒，为 0 时使用默认值
+type ProxyTransportConfig struct {
+	DialTimeoutSeconds           int `yaml:"dial_timeout_seconds"`
+	KeepAliveSeconds             int `yaml:"keep_alive_seconds"`
+	TLSHandshakeTimeoutSeconds   int `yaml:"tls_handshake_timeout_seconds"`
+	ResponseHeaderTimeoutSeconds int `yaml:"response_header_timeout_seconds"`
+	IdleConnTimeoutSeconds       int `yaml:"idle_conn_timeout_seconds"`
+	MaxIdleConns                 int `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost          int `yaml:"max_idle_conns_per_host"`
+	// 上游 HTTP 代理地址，为空时读取 HTTPS_PROXY 等环境变量
+	UpstreamProxy string `yaml:"upstream_proxy"`
+}
+
+// ProxyRule 代理白名单规则
+type ProxyRule struct {
+	// 允许的请求方法，为空表示仅允许 GET
+	Methods []string `yaml:"methods"`
+	// 路径模式，语法同 path.Match，* 匹配单个路径段
+	Path string `yaml:"path"`
 }
 
 // LoggerConfig 日志配置
@@ -48,28 +175,50 @@ type LoggerConfig struct {
 	Format string `yaml:"format"`
 }
 
-// LoadConfig 加载配置
-func LoadConfig() (*Config, error) {
-	// 从 conf/config.yaml 加载配置
-	cfgPath := filepath.Join("conf", "config.yaml")
+// DefaultConfigPath 默认配置文件路径（相对于工作目录）
+const DefaultConfigPath = "conf/config.yaml"
+
+// LoadConfig 加载配置文件，应用 SMART_ELF_* 环境变量覆盖与默认值后校验。
+// 使用默认路径且文件不存在时，仅从环境变量加载；校验失败时返回 *ValidationError
+func LoadConfig(cfgPath string) (*Config, error) {
+	if cfgPath == "" {
+		cfgPath = DefaultConfigPath
+	}
+
+	var config Config
 	data, err := os.ReadFile(cfgPath)
-	if err != nil {
+	switch {
+	case err == nil:
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			return nil, fmt.Errorf("解析配置文件失败: %w", err)
+		}
+	case errors.Is(err, fs.ErrNotExist) && cfgPath == DefaultConfigPath:
+		log.Printf("信息: 未找到配置文件 %s，仅从环境变量加载配置", cfgPath)
+	default:
 		return nil, fmt.Errorf("读取配置文件失败: %w", err)
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	applied, err := applyEnvOverrides(&config)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 {
+		log.Printf("信息: 环境变量覆盖配置: %s", strings.Join(applied, ", "))
+	}
+
+	config.ApplyDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
-	log.Printf("配置加载完成: port=%d, host=%s, log_level=%s, db_debug=%v",
-		config.Server.Port, config.Server.Host, config.Logger.Level, config.Database.Debug)
+	log.Printf("配置加载完成: path=%s, port=%d, host=%s, log_level=%s, db_debug=%v",
+		cfgPath, config.Server.Port, config.Server.Host, config.Logger.Level, config.Database.Debug)
 
 	return &config, nil
 }
 
-// 全局单例配置
-var globalConfig *Config
+// 全局单例配置，热更新时整体替换指针，读取方拿到的配置不会被修改
+var globalConfig atomic.Pointer[Config]
 var globalOnce sync.Once
 
 // LoadGlobalConfig 以单例方式加载配置（仅首次读取文件）
@@ -77,23 +226,23 @@ func LoadGlobalConfig() (*Config, error) {
 	var loadErr error
 	globalOnce.Do(func() {
 		var cfg *Config
-		cfg, loadErr = LoadConfig()
+		cfg, loadErr = LoadConfig(DefaultConfigPath)
 		if loadErr == nil {
-			globalConfig = cfg
+			globalConfig.Store(cfg)
 		}
 	})
-	if globalConfig == nil {
-		return nil, loadErr
+	if cfg := globalConfig.Load(); cfg != nil {
+		return cfg, nil
 	}
-	return globalConfig, nil
+	return nil, loadErr
 }
 
 // SetGlobalConfig 设置全局配置实例（已加载的配置）
 func SetGlobalConfig(cfg *Config) {
-	globalConfig = cfg
+	globalConfig.Store(cfg)
 }
 
 // GetConfig 获取全局配置实例（未初始化时返回nil）
 func GetConfig() *Config {
-	return globalConfig
+	return globalConfig.Load()
 }
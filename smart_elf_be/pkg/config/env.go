@@ -0,0 +1,102 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix 配置项环境变量前缀。
+// 变量名为前缀加 YAML 路径的大写形式，如 feishu.plugin_secret 对应 SMART_ELF_FEISHU_PLUGIN_SECRET；
+// 变量名追加 _FILE 时从该文件读取取值，便于挂载容器密钥
+const EnvPrefix = "SMART_ELF_"
+
+// legacyEnvAliases 兼容旧版本的环境变量
+var legacyEnvAliases = map[string]string{
+	"LOG_LEVEL": EnvPrefix + "LOGGER_LEVEL",
+}
+
+// applyEnvOverrides 使用环境变量覆盖配置，返回生效的变量名
+func applyEnvOverrides(cfg *Config) ([]string, error) {
+	var applied []string
+	if err := applyEnvStruct(reflect.ValueOf(cfg).Elem(), EnvPrefix, &applied); err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// applyEnvStruct 按 yaml 标签递归覆盖结构体字段
+func applyEnvStruct(v reflect.Value, prefix string, applied *[]string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		key := prefix + strings.ToUpper(name)
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvStruct(field, key+"_", applied); err != nil {
+				return err
+			}
+			continue
+		}
+
+		value, source, ok, err := lookupEnv(key)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := setField(field, value); err != nil {
+			return fmt.Errorf("环境变量 %s 取值无效: %w", source, err)
+		}
+		*applied = append(*applied, source)
+	}
+	return nil
+}
+
+// lookupEnv 读取环境变量，依次查找变量本身、_FILE 变体与旧版别名
+func lookupEnv(key string) (value, source string, ok bool, err error) {
+	value, hasValue := os.LookupEnv(key)
+	path, hasFile := os.LookupEnv(key + "_FILE")
+	switch {
+	case hasValue && hasFile:
+		return "", "", false, fmt.Errorf("环境变量 %s 与 %s_FILE 不能同时设置", key, key)
+	case hasValue:
+		return value, key, true, nil
+	case hasFile:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", "", false, fmt.Errorf("读取 %s_FILE 指定的文件失败: %w", key, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), key + "_FILE", true, nil
+	}
+	for alias, target := range legacyEnvAliases {
+		if target != key {
+			continue
+		}
+		if value, ok := os.LookupEnv(alias); ok {
+			return value, alias, true, nil
+		}
+	}
+	return "", "", false, nil
+}
+
+// setField 写入字段，字符串直接赋值，其他类型（数字、布尔、列表）按 YAML 解析
+func setField(field reflect.Value, value string) error {
+	if field.Kind() == reflect.String {
+		field.SetString(value)
+		return nil
+	}
+	target := reflect.New(field.Type())
+	if err := yaml.Unmarshal([]byte(value), target.Interface()); err != nil {
+		return err
+	}
+	field.Set(target.Elem())
+	return nil
+}
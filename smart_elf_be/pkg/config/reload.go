@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// reloadMu 串行化热更新，保证监听方按顺序收到配置
+var reloadMu sync.Mutex
+
+// reloadListeners 热更新监听方
+var reloadListeners []func(cfg *Config)
+
+// OnReload 注册热更新监听方，配置替换后按注册顺序调用
+func OnReload(fn func(cfg *Config)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadListeners = append(reloadListeners, fn)
+}
+
+// Reload 重新加载配置文件并替换全局配置中可热更新的部分：feishu（地址、插件凭证、令牌、超时）与 logger.level。
+// 其他配置段的变化需要重启生效，仅打印警告。加载或校验失败时保留当前配置
+func Reload(cfgPath string) (*Config, error) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+
+	current := globalConfig.Load()
+	if current == nil {
+		return nil, errors.New("config not initialized")
+	}
+	next, err := LoadConfig(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := *current
+	merged.Feishu = next.Feishu
+	merged.Logger.Level = next.Logger.Level
+
+	if ignored := restartOnlyChanges(current, next); len(ignored) > 0 {
+		log.Printf("警告: 以下配置变更需重启后生效: %s", strings.Join(ignored, ", "))
+	}
+
+	globalConfig.Store(&merged)
+	for _, fn := range reloadListeners {
+		fn(&merged)
+	}
+	log.Printf("信息: 配置已热更新: path=%s, log_level=%s", cfgPath, merged.Logger.Level)
+	return &merged, nil
+}
+
+// restartOnlyChanges 列出发生变化但不支持热更新的配置段
+func restartOnlyChanges(current, next *Config) []string {
+	var changed []string
+	cv, nv := reflect.ValueOf(*current), reflect.ValueOf(*next)
+	t := cv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		switch name {
+		case "feishu":
+			continue
+		case "logger":
+			if current.Logger.Format != next.Logger.Format {
+				changed = append(changed, "logger.format")
+			}
+			continue
+		}
+		if !reflect.DeepEqual(cv.Field(i).Interface(), nv.Field(i).Interface()) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,214 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// placeholderValues 示例配置中常见的占位取值，不能作为插件凭证
+var placeholderValues = map[string]bool{
+	"0": true, "1": true, "xxx": true, "changeme": true, "your_plugin_id": true, "your_plugin_secret": true,
+}
+
+// databaseSchemes 数据库连接串支持的协议
+var databaseSchemes = map[string]bool{
+	"sqlite": true, "sqlite3": true, "mysql": true, "postgres": true, "postgresql": true,
+}
+
+// logLevels 支持的日志级别
+var logLevels = map[string]bool{
+	"trace": true, "debug": true, "info": true, "warn": true, "error": true, "fatal": true, "panic": true, "disabled": true,
+}
+
+// proxyMethods 代理白名单允许配置的请求方法
+var proxyMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+}
+
+// ValidationError 配置校验失败，汇总全部问题
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("配置校验失败，共 %d 个问题:\n  - %s", len(e.Problems), strings.Join(e.Problems, "\n  - "))
+}
+
+// validator 收集校验问题
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, field+": "+fmt.Sprintf(format, args...))
+}
+
+// nonNegative 校验数值不小于 0
+func (v *validator) nonNegative(field string, value int) {
+	if value < 0 {
+		v.addf(field, "不能为负数，当前为 %d", value)
+	}
+}
+
+// httpURL 校验 http(s) 绝对地址
+func (v *validator) httpURL(field, value string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(field, "必须是 http(s) 地址，当前为 %q", value)
+	}
+}
+
+// oneOf 校验取值在可选范围内
+func (v *validator) oneOf(field, value string, options ...string) {
+	for _, o := range options {
+		if value == o {
+			return
+		}
+	}
+	v.addf(field, "只能为 %s，当前为 %q", strings.Join(options, "、"), value)
+}
+
+// ApplyDefaults 为未配置的可选项填充默认值
+func (c *Config) ApplyDefaults() {
+	if c.Server.Host == "" {
+		c.Server.Host = "0.0.0.0"
+	}
+	if c.Server.ShutdownTimeoutSeconds == 0 {
+		c.Server.ShutdownTimeoutSeconds = 15
+	}
+	if c.Server.DrainTimeoutSeconds == 0 {
+		c.Server.DrainTimeoutSeconds = 15
+	}
+	if c.Database.MaxIdleConns == 0 {
+		c.Database.MaxIdleConns = 10
+	}
+	if c.Database.MaxOpenConns == 0 {
+		c.Database.MaxOpenConns = 100
+	}
+	if c.Feishu.IMOpenAPIHost == "" {
+		c.Feishu.IMOpenAPIHost = "https://open.feishu.cn"
+	}
+	if c.Feishu.ProjectAPIHost == "" {
+		c.Feishu.ProjectAPIHost = "https://project.feishu.cn"
+	}
+	if c.Feishu.ProjectWebHost == "" {
+		c.Feishu.ProjectWebHost = c.Feishu.ProjectAPIHost
+	}
+	if c.Feishu.PluginToken.Type == "" {
+		c.Feishu.PluginToken.Type = "plugin"
+	}
+	if c.Logger.Level == "" {
+		c.Logger.Level = "info"
+	}
+	if c.Logger.Format == "" {
+		c.Logger.Format = "json"
+	}
+	if c.Duplicate.Action == "" {
+		c.Duplicate.Action = "watcher"
+	}
+	if c.Group.CloseAction == "" {
+		c.Group.CloseAction = "dissolve"
+	}
+}
+
+// Validate 校验配置，返回汇总全部问题的 *ValidationError
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		v.addf("server.port", "必须在 1~65535 之间，当前为 %d", c.Server.Port)
+	}
+	v.nonNegative("server.shutdown_timeout_seconds", c.Server.ShutdownTimeoutSeconds)
+	v.nonNegative("server.drain_timeout_seconds", c.Server.DrainTimeoutSeconds)
+
+	if strings.TrimSpace(c.Database.DSN) == "" {
+		v.addf("database.dsn", "不能为空")
+	} else if scheme, _, ok := strings.Cut(c.Database.DSN, "://"); ok && !databaseSchemes[strings.ToLower(scheme)] {
+		v.addf("database.dsn", "不支持的协议 %q，只能为 sqlite://、mysql://、postgres://", scheme)
+	}
+	v.nonNegative("database.max_idle_conns", c.Database.MaxIdleConns)
+	v.nonNegative("database.max_open_conns", c.Database.MaxOpenConns)
+	v.nonNegative("database.conn_max_lifetime_seconds", c.Database.ConnMaxLifetimeSeconds)
+	v.nonNegative("database.conn_max_idle_time_seconds", c.Database.ConnMaxIdleTimeSeconds)
+
+	v.httpURL("feishu.im_open_api_host", c.Feishu.IMOpenAPIHost)
+	v.httpURL("feishu.project_api_host", c.Feishu.ProjectAPIHost)
+	v.httpURL("feishu.project_web_host", c.Feishu.ProjectWebHost)
+	if c.Feishu.PluginID == "" || placeholderValues[strings.ToLower(c.Feishu.PluginID)] {
+		v.addf("feishu.plugin_id", "未配置或为示例占位值 %q，请填写飞书项目插件 ID", c.Feishu.PluginID)
+	}
+	if c.Feishu.PluginSecret == "" || placeholderValues[strings.ToLower(c.Feishu.PluginSecret)] {
+		v.addf("feishu.plugin_secret", "未配置或为示例占位值，请填写飞书项目插件密钥")
+	}
+	v.oneOf("feishu.plugin_token.type", c.Feishu.PluginToken.Type, "plugin", "virtual_plugin")
+	v.nonNegative("feishu.plugin_token.refresh_before_seconds", c.Feishu.PluginToken.RefreshBeforeSeconds)
+	v.nonNegative("feishu.plugin_token.refresh_jitter_seconds", c.Feishu.PluginToken.RefreshJitterSeconds)
+	v.nonNegative("feishu.timeout.contact_seconds", c.Feishu.Timeout.ContactSeconds)
+	v.nonNegative("feishu.timeout.im_seconds", c.Feishu.Timeout.IMSeconds)
+	v.nonNegative("feishu.timeout.project_seconds", c.Feishu.Timeout.ProjectSeconds)
+	v.nonNegative("feishu.timeout.work_item_seconds", c.Feishu.Timeout.WorkItemSeconds)
+
+	if !logLevels[strings.ToLower(c.Logger.Level)] {
+		v.addf("logger.level", "无效的日志级别 %q", c.Logger.Level)
+	}
+	v.oneOf("logger.format", c.Logger.Format, "json", "console")
+
+	for _, r := range []struct {
+		name string
+		rule RateLimitRule
+	}{
+		{"rate_limit.per_sender", c.RateLimit.PerSender},
+		{"rate_limit.per_chat", c.RateLimit.PerChat},
+		{"rate_limit.per_project", c.RateLimit.PerProject},
+	} {
+		v.nonNegative(r.name+".capacity", r.rule.Capacity)
+		if r.rule.RefillPerMinute < 0 {
+			v.addf(r.name+".refill_per_minute", "不能为负数，当前为 %v", r.rule.RefillPerMinute)
+		}
+	}
+
+	if c.Duplicate.Threshold < 0 || c.Duplicate.Threshold > 1 {
+		v.addf("duplicate.threshold", "必须在 0~1 之间，当前为 %v", c.Duplicate.Threshold)
+	}
+	v.nonNegative("duplicate.window_minutes", c.Duplicate.WindowMinutes)
+	v.oneOf("duplicate.action", c.Duplicate.Action, "watcher", "comment")
+
+	v.nonNegative("group.reconcile_interval_minutes", c.Group.ReconcileIntervalMinutes)
+	v.nonNegative("group.close_delay_minutes", c.Group.CloseDelayMinutes)
+	v.oneOf("group.close_action", c.Group.CloseAction, "dissolve", "leave")
+
+	v.nonNegative("step.max_attempts", c.Step.MaxAttempts)
+	v.nonNegative("step.backoff_seconds", c.Step.BackoffSeconds)
+	v.nonNegative("step.max_backoff_seconds", c.Step.MaxBackoffSeconds)
+	v.nonNegative("step.retry_interval_seconds", c.Step.RetryIntervalSeconds)
+	v.nonNegative("metadata.ttl_minutes", c.Metadata.TTLMinutes)
+
+	v.nonNegative("proxy.session_ttl_minutes", c.Proxy.SessionTTLMinutes)
+	for i, rule := range c.Proxy.Allow {
+		field := fmt.Sprintf("proxy.allow[%d]", i)
+		if !strings.HasPrefix(rule.Path, "/") {
+			v.addf(field+".path", "必须以 / 开头，当前为 %q", rule.Path)
+		} else if _, err := path.Match(rule.Path, ""); err != nil {
+			v.addf(field+".path", "无效的路径模式 %q", rule.Path)
+		}
+		for _, m := range rule.Methods {
+			if !proxyMethods[strings.ToUpper(m)] {
+				v.addf(field+".methods", "不支持的请求方法 %q", m)
+			}
+		}
+	}
+	if upstream := c.Proxy.Transport.UpstreamProxy; upstream != "" {
+		if u, err := url.Parse(upstream); err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			v.addf("proxy.transport.upstream_proxy", "必须是 http(s) 或 socks5 地址，当前为 %q", upstream)
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
@@ -2,11 +2,12 @@ package database
 
 import (
 	"log"
-	"smart_elf_standalone/internal/model"
+	"os"
+	"path/filepath"
 	"smart_elf_standalone/pkg/config"
+	"strings"
+	"time"
 
-	"gorm.io/driver/mysql"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -24,20 +25,25 @@ func InitDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 		Logger: logger.Default.LogMode(logLevel),
 	}
 
-	var db *gorm.DB
-	var err error
-
-	// 根据DSN判断使用MySQL还是SQLite
-	if cfg.DSN == "sqlite://./smart_elf.db" {
-		// 使用SQLite
-		log.Printf("信息: 使用SQLite数据库: ./smart_elf.db")
-		db, err = gorm.Open(sqlite.Open("./smart_elf.db"), gormConfig)
-	} else {
-		// 使用MySQL
-		log.Printf("信息: 使用MySQL数据库")
-		db, err = gorm.Open(mysql.Open(cfg.DSN), gormConfig)
+	// 根据DSN协议选择数据库驱动
+	dsn, err := ParseDSN(cfg.DSN)
+	if err != nil {
+		log.Printf("错误: 解析数据库连接串失败: %v", err)
+		return nil, err
+	}
+	if !strings.Contains(cfg.DSN, "://") {
+		log.Printf("警告: 数据库连接串未指定协议，按MySQL处理，建议改为 mysql:// 形式")
 	}
+	if path := dsn.sqlitePath(); path != "" {
+		// 数据库文件所在目录不存在时创建
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			log.Printf("错误: 创建SQLite数据库目录失败: %v", err)
+			return nil, err
+		}
+	}
+	log.Printf("信息: 使用数据库: driver=%s, target=%s", dsn.Driver, dsn.Target)
 
+	db, err := gorm.Open(dsn.Dialector(), gormConfig)
 	if err != nil {
 		log.Printf("错误: 连接数据库失败: %v", err)
 		return nil, err
@@ -53,30 +59,15 @@ func InitDB(cfg *config.DatabaseConfig) (*gorm.DB, error) {
 	// 设置连接池参数
 	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
 	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetimeSeconds) * time.Second)
+	sqlDB.SetConnMaxIdleTime(time.Duration(cfg.ConnMaxIdleTimeSeconds) * time.Second)
 
-	log.Printf("信息: 数据库连接池配置完成: max_idle_conns=%d, max_open_conns=%d, debug=%v", cfg.MaxIdleConns, cfg.MaxOpenConns, cfg.Debug)
+	log.Printf("信息: 数据库连接池配置完成: max_idle_conns=%d, max_open_conns=%d, conn_max_lifetime=%ds, conn_max_idle_time=%ds, debug=%v",
+		cfg.MaxIdleConns, cfg.MaxOpenConns, cfg.ConnMaxLifetimeSeconds, cfg.ConnMaxIdleTimeSeconds, cfg.Debug)
 
 	return db, nil
 }
 
-// AutoMigrate 自动迁移数据库表结构
-func AutoMigrate(db *gorm.DB) error {
-	log.Printf("信息: 开始数据库迁移")
-
-	// 迁移模型
-	err := db.AutoMigrate(
-		&model.AppConfig{},
-	)
-
-	if err != nil {
-		log.Printf("错误: 数据库迁移失败: %v", err)
-		return err
-	}
-
-	log.Printf("信息: 数据库迁移成功")
-	return nil
-}
-
 // CloseDB 关闭数据库连接
 func CloseDB(db *gorm.DB) error {
 	sqlDB, err := db.DB()
@@ -0,0 +1,261 @@
+package database
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationFiles 按数据库类型分目录存放的迁移脚本，文件名为 <版本>_<名称>.up.sql / .down.sql
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration 单个版本的表结构迁移
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState 迁移的应用状态
+type MigrationState struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Missing 数据库中已应用但当前程序中不存在的迁移，通常说明数据库由更新版本的程序迁移过
+	Missing bool
+}
+
+// schemaMigration 迁移记录表
+type schemaMigration struct {
+	Version   int64     `gorm:"column:version;primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"column:name;size:255"`
+	AppliedAt time.Time `gorm:"column:applied_at"`
+}
+
+// TableName 指定表名
+func (schemaMigration) TableName() string {
+	return "smart_elf_schema_migrations"
+}
+
+// LoadMigrations 读取指定数据库类型的迁移脚本，按版本升序返回
+func LoadMigrations(driver string) ([]*Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database driver %q: %w", driver, err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		base, direction, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), ".")
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") || !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name: %s", path.Join(dir, name))
+		}
+		versionStr, migrationName, _ := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version: %s", path.Join(dir, name))
+		}
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: migrationName}
+			byVersion[version] = m
+		} else if m.Name != migrationName {
+			return nil, fmt.Errorf("migration version %d has conflicting names: %s, %s", version, m.Name, migrationName)
+		}
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down scripts", m.Version, m.Name)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp 按版本顺序应用全部未应用的迁移，返回本次应用的数量
+func MigrateUp(db *gorm.DB) (int, error) {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		log.Printf("信息: 应用数据库迁移: %04d_%s", m.Version, m.Name)
+		if check := migrationChecks[m.Version]; check != nil {
+			if err := check(db); err != nil {
+				log.Printf("错误: 数据库迁移前置检查未通过: %04d_%s: %v", m.Version, m.Name, err)
+				return count, fmt.Errorf("migration %04d_%s precheck failed: %w", m.Version, m.Name, err)
+			}
+		}
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, m.Up); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			log.Printf("错误: 数据库迁移失败: %04d_%s: %v", m.Version, m.Name, err)
+			return count, fmt.Errorf("migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrateDown 按版本倒序回滚最近应用的 steps 个迁移，返回本次回滚的数量
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	if steps <= 0 {
+		return 0, fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		m := migrations[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		log.Printf("信息: 回滚数据库迁移: %04d_%s", m.Version, m.Name)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := execScript(tx, m.Down); err != nil {
+				return err
+			}
+			return tx.Where("version = ?", m.Version).Delete(&schemaMigration{}).Error
+		})
+		if err != nil {
+			log.Printf("错误: 数据库迁移回滚失败: %04d_%s: %v", m.Version, m.Name, err)
+			return count, fmt.Errorf("rollback of migration %04d_%s failed: %w", m.Version, m.Name, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrationStatus 返回全部迁移的应用状态，按版本升序
+func MigrationStatus(db *gorm.DB) ([]*MigrationState, error) {
+	migrations, applied, err := loadMigrationState(db)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := &MigrationState{Version: m.Version, Name: m.Name}
+		if record, ok := applied[m.Version]; ok {
+			state.Applied = true
+			state.AppliedAt = &record.AppliedAt
+			delete(applied, m.Version)
+		}
+		states = append(states, state)
+	}
+	for _, record := range applied {
+		appliedAt := record.AppliedAt
+		states = append(states, &MigrationState{
+			Version:   record.Version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, nil
+}
+
+// loadMigrationState 读取当前数据库类型的迁移脚本与已应用的迁移记录
+func loadMigrationState(db *gorm.DB) ([]*Migration, map[int64]*schemaMigration, error) {
+	migrations, err := LoadMigrations(db.Dialector.Name())
+	if err != nil {
+		return nil, nil, err
+	}
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		if err := db.Migrator().CreateTable(&schemaMigration{}); err != nil {
+			log.Printf("错误: 创建迁移记录表失败: %v", err)
+			return nil, nil, err
+		}
+	}
+
+	var records []*schemaMigration
+	if err := db.Order("version").Find(&records).Error; err != nil {
+		log.Printf("错误: 查询迁移记录失败: %v", err)
+		return nil, nil, err
+	}
+	applied := make(map[int64]*schemaMigration, len(records))
+	for _, r := range records {
+		applied[r.Version] = r
+	}
+	return migrations, applied, nil
+}
+
+// execScript 逐条执行迁移脚本。语句以行尾分号结束，-- 开头的行为注释。
+// CREATE TABLE IF NOT EXISTS 遇到已存在的表时补齐缺少的列与索引，见 adoptTable。
+// MySQL 的 DDL 会隐式提交事务，脚本中途失败后重试时跳过已生效的 DDL，见 ignoreAppliedDDL
+func execScript(tx *gorm.DB, script string) error {
+	for _, stmt := range splitStatements(script) {
+		if err := ignoreAppliedDDL(tx, tx.Exec(stmt).Error); err != nil {
+			return err
+		}
+		if def := parseCreateTable(stmt); def != nil {
+			if err := adoptTable(tx, def); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitStatements 按行尾分号拆分 SQL 脚本
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		stmts = append(stmts, rest)
+	}
+	return stmts
+}
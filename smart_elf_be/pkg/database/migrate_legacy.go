@@ -0,0 +1,144 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// 引入版本化迁移之前，表结构由各版本程序的 AutoMigrate 或 init_db.sql 创建，已有的表可能缺少后续版本新增的列。
+// 初始迁移使用 CREATE TABLE IF NOT EXISTS，表已存在时按建表语句补齐缺少的列与索引
+
+var (
+	createTablePattern = regexp.MustCompile("(?i)^CREATE TABLE IF NOT EXISTS [`\"]?(\\w+)[`\"]?\\s*\\(")
+	columnPattern      = regexp.MustCompile("^[`\"](\\w+)[`\"]\\s+(.+?),?$")
+	indexPattern       = regexp.MustCompile("(?i)^(UNIQUE\\s+)?INDEX\\s+[`\"](\\w+)[`\"]\\s*\\(([^)]+)\\),?$")
+)
+
+// tableDef 建表语句中的列与内联索引
+type tableDef struct {
+	name    string
+	columns []columnDef
+	indexes []indexDef
+}
+
+type columnDef struct {
+	name       string
+	definition string
+}
+
+type indexDef struct {
+	name    string
+	unique  bool
+	columns string
+}
+
+// parseCreateTable 解析 CREATE TABLE IF NOT EXISTS 语句，其他语句返回 nil
+func parseCreateTable(stmt string) *tableDef {
+	lines := strings.Split(stmt, "\n")
+	m := createTablePattern.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if m == nil {
+		return nil
+	}
+	def := &tableDef{name: m[1]}
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if im := indexPattern.FindStringSubmatch(line); im != nil {
+			def.indexes = append(def.indexes, indexDef{name: im[2], unique: im[1] != "", columns: im[3]})
+			continue
+		}
+		cm := columnPattern.FindStringSubmatch(line)
+		// 主键列随建表创建，已存在的表一定包含
+		if cm == nil || strings.Contains(strings.ToUpper(cm[2]), "PRIMARY KEY") {
+			continue
+		}
+		def.columns = append(def.columns, columnDef{name: cm[1], definition: cm[2]})
+	}
+	return def
+}
+
+// adoptTable 为已存在的表补齐建表语句中缺少的列与内联索引，新建的表不会有缺失
+func adoptTable(tx *gorm.DB, def *tableDef) error {
+	migrator := tx.Migrator()
+	for _, col := range def.columns {
+		if migrator.HasColumn(def.name, col.name) {
+			continue
+		}
+		log.Printf("信息: 补齐缺少的列: %s.%s", def.name, col.name)
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", quote(tx, def.name), quote(tx, col.name), col.definition)
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("add column %s.%s: %w", def.name, col.name, err)
+		}
+	}
+	for _, idx := range def.indexes {
+		if migrator.HasIndex(def.name, idx.name) {
+			continue
+		}
+		log.Printf("信息: 补齐缺少的索引: %s.%s", def.name, idx.name)
+		kind := "INDEX"
+		if idx.unique {
+			kind = "UNIQUE INDEX"
+		}
+		stmt := fmt.Sprintf("CREATE %s %s ON %s (%s)", kind, quote(tx, idx.name), quote(tx, def.name), idx.columns)
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("create index %s.%s: %w", def.name, idx.name, err)
+		}
+	}
+	return nil
+}
+
+// quote 按数据库类型为标识符加引号
+func quote(tx *gorm.DB, name string) string {
+	var b strings.Builder
+	tx.Dialector.QuoteTo(&b, name)
+	return b.String()
+}
+
+// mysqlAppliedDDLErrors 表示 DDL 已生效的 MySQL 错误码：重复的列、重复的索引名、要删除的列或索引不存在
+var mysqlAppliedDDLErrors = map[uint16]bool{1060: true, 1061: true, 1091: true}
+
+// ignoreAppliedDDL MySQL 的 DDL 不随事务回滚，迁移中途失败后重试时，已生效的 DDL 会报错；
+// 这类错误视为已执行并跳过，使迁移可以安全重试
+func ignoreAppliedDDL(tx *gorm.DB, err error) error {
+	var mysqlErr *mysqldriver.MySQLError
+	if err == nil || tx.Dialector.Name() != DriverMySQL || !errors.As(err, &mysqlErr) || !mysqlAppliedDDLErrors[mysqlErr.Number] {
+		return err
+	}
+	log.Printf("警告: 跳过已生效的 DDL: %v", err)
+	return nil
+}
+
+// migrationChecks 应用迁移前的数据检查，按版本号注册
+var migrationChecks = map[int64]func(db *gorm.DB) error{
+	2: checkConfigDuplicates,
+}
+
+// checkConfigDuplicates 检查配置表中未删除记录的 project_key 与 signature 是否重复，
+// 重复时无法建立唯一索引，需人工确认保留哪条配置
+func checkConfigDuplicates(db *gorm.DB) error {
+	var problems []string
+	for _, column := range []string{"project_key", "signature"} {
+		var values []string
+		err := db.Table("smart_elf").
+			Select(column).
+			Where("deleted_at IS NULL AND " + column + " IS NOT NULL AND " + column + " <> ''").
+			Group(column).
+			Having("COUNT(*) > 1").
+			Pluck(column, &values).Error
+		if err != nil {
+			return err
+		}
+		if len(values) > 0 {
+			problems = append(problems, fmt.Sprintf("%s 重复: %s", column, strings.Join(values, ", ")))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("配置表 smart_elf 存在多条未删除的重复配置（%s），请合并或删除多余记录后重试", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+-- 初始表结构，与此前 GORM AutoMigrate 创建的结构一致；表已存在时补齐缺少的列与索引
+
+CREATE TABLE IF NOT EXISTS `smart_elf` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `bot_id` longtext,
+    `bot_secret` longtext,
+    `bot_verification_token` longtext,
+    `project_key` longtext,
+    `tenant_key` longtext,
+    `work_item_type_key` longtext,
+    `work_item_api_name` longtext,
+    `work_item_template_id` bigint,
+    `creator_field_key` longtext,
+    `reply_switch` boolean,
+    `create_group_switch` boolean,
+    `signature` longtext,
+    `api_user_key` longtext,
+    `enabled` boolean DEFAULT true,
+    `group_name_template` longtext,
+    `group_role_key` longtext,
+    `group_oncall_open_ids` longtext,
+    `group_invite_mentions` boolean,
+    `alert_chat_id` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_smart_elf_deleted_at` (`deleted_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_rate_limit_bucket` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `bucket_key` varchar(255),
+    `tokens` double,
+    `refilled_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE INDEX `idx_smart_elf_rate_limit_bucket_bucket_key` (`bucket_key`)
+);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_ticket_index` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `project_key` varchar(255),
+    `work_item_type_key` longtext,
+    `work_item_id` bigint,
+    `title` longtext,
+    `normalized_title` longtext,
+    PRIMARY KEY (`id`),
+    INDEX `idx_smart_elf_ticket_index_created_at` (`created_at`),
+    INDEX `idx_smart_elf_ticket_index_project_key` (`project_key`)
+);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_ticket_group` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `project_key` varchar(255),
+    `work_item_type_key` longtext,
+    `work_item_id` bigint,
+    `chat_id` varchar(255),
+    `name_cn` longtext,
+    `name_en` longtext,
+    `status` varchar(32),
+    `resolved_at` datetime(3) NULL,
+    `closed_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_smart_elf_ticket_group_deleted_at` (`deleted_at`),
+    INDEX `idx_smart_elf_ticket_group_project_key` (`project_key`),
+    INDEX `idx_smart_elf_ticket_group_work_item_id` (`work_item_id`),
+    INDEX `idx_smart_elf_ticket_group_chat_id` (`chat_id`),
+    INDEX `idx_smart_elf_ticket_group_status` (`status`)
+);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_ticket` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `project_key` varchar(255),
+    `work_item_type_key` longtext,
+    `work_item_id` bigint,
+    `title` text,
+    `source_message_id` varchar(255),
+    `source_chat_id` varchar(255),
+    `source_chat_type` longtext,
+    `source_thread_id` longtext,
+    `reporter_open_id` varchar(255),
+    `reporter_union_id` longtext,
+    `reporter_name` longtext,
+    `group_chat_id` longtext,
+    `status` varchar(32),
+    `err_msg` text,
+    `first_response_at` datetime(3) NULL,
+    `closed_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_smart_elf_ticket_deleted_at` (`deleted_at`),
+    INDEX `idx_smart_elf_ticket_project_key` (`project_key`),
+    INDEX `idx_smart_elf_ticket_work_item_id` (`work_item_id`),
+    INDEX `idx_smart_elf_ticket_source_message_id` (`source_message_id`),
+    INDEX `idx_smart_elf_ticket_source_chat_id` (`source_chat_id`),
+    INDEX `idx_smart_elf_ticket_reporter_open_id` (`reporter_open_id`),
+    INDEX `idx_smart_elf_ticket_status` (`status`)
+);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_ticket_step` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `ticket_id` bigint unsigned,
+    `project_key` varchar(255),
+    `work_item_type_key` longtext,
+    `work_item_id` bigint,
+    `step_type` varchar(32),
+    `status` varchar(32),
+    `attempts` bigint,
+    `next_retry_at` datetime(3) NULL,
+    `payload` text,
+    `result` longtext,
+    `err_msg` text,
+    PRIMARY KEY (`id`),
+    INDEX `idx_smart_elf_ticket_step_deleted_at` (`deleted_at`),
+    INDEX `idx_smart_elf_ticket_step_ticket_id` (`ticket_id`),
+    INDEX `idx_smart_elf_ticket_step_project_key` (`project_key`),
+    INDEX `idx_smart_elf_ticket_step_work_item_id` (`work_item_id`),
+    INDEX `idx_smart_elf_ticket_step_status` (`status`),
+    INDEX `idx_smart_elf_ticket_step_next_retry_at` (`next_retry_at`)
+);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_user_token` (
+    `id` bigint unsigned AUTO_INCREMENT,
+    `created_at` datetime(3) NULL,
+    `updated_at` datetime(3) NULL,
+    `deleted_at` datetime(3) NULL,
+    `user_key` varchar(255),
+    `access_token` text,
+    `expire_at` datetime(3) NULL,
+    `refresh_token` text,
+    `refresh_expire_at` datetime(3) NULL,
+    PRIMARY KEY (`id`),
+    INDEX `idx_smart_elf_user_token_deleted_at` (`deleted_at`),
+    UNIQUE INDEX `idx_smart_elf_user_token_user_key` (`user_key`)
+);
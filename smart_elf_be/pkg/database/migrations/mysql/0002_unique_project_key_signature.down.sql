@@ -0,0 +1,7 @@
+-- 已清理的软删除记录不会恢复
+ALTER TABLE `smart_elf` DROP INDEX `idx_smart_elf_signature`;
+ALTER TABLE `smart_elf` DROP INDEX `idx_smart_elf_project_key`;
+
+ALTER TABLE `smart_elf`
+    MODIFY `project_key` longtext,
+    MODIFY `signature` longtext;
@@ -0,0 +1,22 @@
+-- 配置表按 project_key 与 signature 唯一
+
+-- 空签名视为未生成，避免与唯一约束冲突
+UPDATE `smart_elf` SET `signature` = NULL WHERE `signature` = '';
+
+-- 同一项目保留未删除的记录，均已删除时保留最新一条，清理多余的软删除记录；
+-- 多条未删除的重复配置无法自动取舍，由迁移前置检查 checkConfigDuplicates 拦截
+DELETE r FROM `smart_elf` r
+JOIN `smart_elf` s
+  ON s.`project_key` = r.`project_key`
+ AND s.`id` <> r.`id`
+ AND (s.`deleted_at` IS NULL OR s.`id` > r.`id`)
+WHERE r.`deleted_at` IS NOT NULL;
+
+-- longtext 列无法建立唯一索引，先改为定长字符串
+ALTER TABLE `smart_elf`
+    MODIFY `project_key` varchar(255),
+    MODIFY `signature` varchar(255);
+
+-- DDL 不随事务回滚，每个索引单独一条语句，中途失败后重试时跳过已建立的索引
+ALTER TABLE `smart_elf` ADD UNIQUE INDEX `idx_smart_elf_project_key` (`project_key`);
+ALTER TABLE `smart_elf` ADD UNIQUE INDEX `idx_smart_elf_signature` (`signature`);
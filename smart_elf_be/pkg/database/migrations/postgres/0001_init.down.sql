@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS "smart_elf_user_token";
+DROP TABLE IF EXISTS "smart_elf_ticket_step";
+DROP TABLE IF EXISTS "smart_elf_ticket";
+DROP TABLE IF EXISTS "smart_elf_ticket_group";
+DROP TABLE IF EXISTS "smart_elf_ticket_index";
+DROP TABLE IF EXISTS "smart_elf_rate_limit_bucket";
+DROP TABLE IF EXISTS "smart_elf";
@@ -0,0 +1,137 @@
+-- 初始表结构，与此前 GORM AutoMigrate 创建的结构一致；表已存在时补齐缺少的列与索引
+
+CREATE TABLE IF NOT EXISTS "smart_elf" (
+    "id" bigserial PRIMARY KEY,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "bot_id" text,
+    "bot_secret" text,
+    "bot_verification_token" text,
+    "project_key" text,
+    "tenant_key" text,
+    "work_item_type_key" text,
+    "work_item_api_name" text,
+    "work_item_template_id" bigint,
+    "creator_field_key" text,
+    "reply_switch" boolean,
+    "create_group_switch" boolean,
+    "signature" text,
+    "api_user_key" text,
+    "enabled" boolean DEFAULT true,
+    "group_name_template" text,
+    "group_role_key" text,
+    "group_oncall_open_ids" text,
+    "group_invite_mentions" boolean,
+    "alert_chat_id" text
+);
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_deleted_at" ON "smart_elf" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "smart_elf_rate_limit_bucket" (
+    "id" bigserial PRIMARY KEY,
+    "bucket_key" varchar(255),
+    "tokens" decimal,
+    "refilled_at" timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_smart_elf_rate_limit_bucket_bucket_key" ON "smart_elf_rate_limit_bucket" ("bucket_key");
+
+CREATE TABLE IF NOT EXISTS "smart_elf_ticket_index" (
+    "id" bigserial PRIMARY KEY,
+    "created_at" timestamptz,
+    "project_key" varchar(255),
+    "work_item_type_key" text,
+    "work_item_id" bigint,
+    "title" text,
+    "normalized_title" text
+);
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_index_created_at" ON "smart_elf_ticket_index" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_index_project_key" ON "smart_elf_ticket_index" ("project_key");
+
+CREATE TABLE IF NOT EXISTS "smart_elf_ticket_group" (
+    "id" bigserial PRIMARY KEY,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "project_key" varchar(255),
+    "work_item_type_key" text,
+    "work_item_id" bigint,
+    "chat_id" varchar(255),
+    "name_cn" text,
+    "name_en" text,
+    "status" varchar(32),
+    "resolved_at" timestamptz,
+    "closed_at" timestamptz
+);
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_group_deleted_at" ON "smart_elf_ticket_group" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_group_project_key" ON "smart_elf_ticket_group" ("project_key");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_group_work_item_id" ON "smart_elf_ticket_group" ("work_item_id");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_group_chat_id" ON "smart_elf_ticket_group" ("chat_id");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_group_status" ON "smart_elf_ticket_group" ("status");
+
+CREATE TABLE IF NOT EXISTS "smart_elf_ticket" (
+    "id" bigserial PRIMARY KEY,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "project_key" varchar(255),
+    "work_item_type_key" text,
+    "work_item_id" bigint,
+    "title" text,
+    "source_message_id" varchar(255),
+    "source_chat_id" varchar(255),
+    "source_chat_type" text,
+    "source_thread_id" text,
+    "reporter_open_id" varchar(255),
+    "reporter_union_id" text,
+    "reporter_name" text,
+    "group_chat_id" text,
+    "status" varchar(32),
+    "err_msg" text,
+    "first_response_at" timestamptz,
+    "closed_at" timestamptz
+);
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_deleted_at" ON "smart_elf_ticket" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_project_key" ON "smart_elf_ticket" ("project_key");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_work_item_id" ON "smart_elf_ticket" ("work_item_id");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_source_message_id" ON "smart_elf_ticket" ("source_message_id");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_source_chat_id" ON "smart_elf_ticket" ("source_chat_id");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_reporter_open_id" ON "smart_elf_ticket" ("reporter_open_id");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_status" ON "smart_elf_ticket" ("status");
+
+CREATE TABLE IF NOT EXISTS "smart_elf_ticket_step" (
+    "id" bigserial PRIMARY KEY,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "ticket_id" bigint,
+    "project_key" varchar(255),
+    "work_item_type_key" text,
+    "work_item_id" bigint,
+    "step_type" varchar(32),
+    "status" varchar(32),
+    "attempts" bigint,
+    "next_retry_at" timestamptz,
+    "payload" text,
+    "result" text,
+    "err_msg" text
+);
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_step_deleted_at" ON "smart_elf_ticket_step" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_step_ticket_id" ON "smart_elf_ticket_step" ("ticket_id");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_step_project_key" ON "smart_elf_ticket_step" ("project_key");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_step_work_item_id" ON "smart_elf_ticket_step" ("work_item_id");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_step_status" ON "smart_elf_ticket_step" ("status");
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_ticket_step_next_retry_at" ON "smart_elf_ticket_step" ("next_retry_at");
+
+CREATE TABLE IF NOT EXISTS "smart_elf_user_token" (
+    "id" bigserial PRIMARY KEY,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    "user_key" varchar(255),
+    "access_token" text,
+    "expire_at" timestamptz,
+    "refresh_token" text,
+    "refresh_expire_at" timestamptz
+);
+CREATE INDEX IF NOT EXISTS "idx_smart_elf_user_token_deleted_at" ON "smart_elf_user_token" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_smart_elf_user_token_user_key" ON "smart_elf_user_token" ("user_key");
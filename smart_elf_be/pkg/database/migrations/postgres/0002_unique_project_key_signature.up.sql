@@ -0,0 +1,18 @@
+-- 配置表按 project_key 与 signature 唯一
+
+-- 空签名视为未生成，避免与唯一约束冲突
+UPDATE "smart_elf" SET "signature" = NULL WHERE "signature" = '';
+
+-- 同一项目保留未删除的记录，均已删除时保留最新一条，清理多余的软删除记录；
+-- 多条未删除的重复配置无法自动取舍，由迁移前置检查 checkConfigDuplicates 拦截
+DELETE FROM "smart_elf"
+WHERE "deleted_at" IS NOT NULL
+  AND EXISTS (
+    SELECT 1 FROM "smart_elf" s
+    WHERE s."project_key" = "smart_elf"."project_key"
+      AND s."id" <> "smart_elf"."id"
+      AND (s."deleted_at" IS NULL OR s."id" > "smart_elf"."id")
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_smart_elf_project_key" ON "smart_elf" ("project_key");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_smart_elf_signature" ON "smart_elf" ("signature");
@@ -0,0 +1,137 @@
+-- 初始表结构，与此前 GORM AutoMigrate 创建的结构一致；表已存在时补齐缺少的列与索引
+
+CREATE TABLE IF NOT EXISTS `smart_elf` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `bot_id` text,
+    `bot_secret` text,
+    `bot_verification_token` text,
+    `project_key` text,
+    `tenant_key` text,
+    `work_item_type_key` text,
+    `work_item_api_name` text,
+    `work_item_template_id` integer,
+    `creator_field_key` text,
+    `reply_switch` numeric,
+    `create_group_switch` numeric,
+    `signature` text,
+    `api_user_key` text,
+    `enabled` numeric DEFAULT true,
+    `group_name_template` text,
+    `group_role_key` text,
+    `group_oncall_open_ids` text,
+    `group_invite_mentions` numeric,
+    `alert_chat_id` text
+);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_deleted_at` ON `smart_elf`(`deleted_at`);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_rate_limit_bucket` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `bucket_key` text,
+    `tokens` real,
+    `refilled_at` datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_smart_elf_rate_limit_bucket_bucket_key` ON `smart_elf_rate_limit_bucket`(`bucket_key`);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_ticket_index` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `project_key` text,
+    `work_item_type_key` text,
+    `work_item_id` integer,
+    `title` text,
+    `normalized_title` text
+);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_index_created_at` ON `smart_elf_ticket_index`(`created_at`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_index_project_key` ON `smart_elf_ticket_index`(`project_key`);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_ticket_group` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `project_key` text,
+    `work_item_type_key` text,
+    `work_item_id` integer,
+    `chat_id` text,
+    `name_cn` text,
+    `name_en` text,
+    `status` text,
+    `resolved_at` datetime,
+    `closed_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_group_deleted_at` ON `smart_elf_ticket_group`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_group_project_key` ON `smart_elf_ticket_group`(`project_key`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_group_work_item_id` ON `smart_elf_ticket_group`(`work_item_id`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_group_chat_id` ON `smart_elf_ticket_group`(`chat_id`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_group_status` ON `smart_elf_ticket_group`(`status`);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_ticket` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `project_key` text,
+    `work_item_type_key` text,
+    `work_item_id` integer,
+    `title` text,
+    `source_message_id` text,
+    `source_chat_id` text,
+    `source_chat_type` text,
+    `source_thread_id` text,
+    `reporter_open_id` text,
+    `reporter_union_id` text,
+    `reporter_name` text,
+    `group_chat_id` text,
+    `status` text,
+    `err_msg` text,
+    `first_response_at` datetime,
+    `closed_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_deleted_at` ON `smart_elf_ticket`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_project_key` ON `smart_elf_ticket`(`project_key`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_work_item_id` ON `smart_elf_ticket`(`work_item_id`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_source_message_id` ON `smart_elf_ticket`(`source_message_id`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_source_chat_id` ON `smart_elf_ticket`(`source_chat_id`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_reporter_open_id` ON `smart_elf_ticket`(`reporter_open_id`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_status` ON `smart_elf_ticket`(`status`);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_ticket_step` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `ticket_id` integer,
+    `project_key` text,
+    `work_item_type_key` text,
+    `work_item_id` integer,
+    `step_type` text,
+    `status` text,
+    `attempts` integer,
+    `next_retry_at` datetime,
+    `payload` text,
+    `result` text,
+    `err_msg` text
+);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_step_deleted_at` ON `smart_elf_ticket_step`(`deleted_at`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_step_ticket_id` ON `smart_elf_ticket_step`(`ticket_id`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_step_project_key` ON `smart_elf_ticket_step`(`project_key`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_step_work_item_id` ON `smart_elf_ticket_step`(`work_item_id`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_step_status` ON `smart_elf_ticket_step`(`status`);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_ticket_step_next_retry_at` ON `smart_elf_ticket_step`(`next_retry_at`);
+
+CREATE TABLE IF NOT EXISTS `smart_elf_user_token` (
+    `id` integer PRIMARY KEY AUTOINCREMENT,
+    `created_at` datetime,
+    `updated_at` datetime,
+    `deleted_at` datetime,
+    `user_key` text,
+    `access_token` text,
+    `expire_at` datetime,
+    `refresh_token` text,
+    `refresh_expire_at` datetime
+);
+CREATE INDEX IF NOT EXISTS `idx_smart_elf_user_token_deleted_at` ON `smart_elf_user_token`(`deleted_at`);
+CREATE UNIQUE INDEX IF NOT EXISTS `idx_smart_elf_user_token_user_key` ON `smart_elf_user_token`(`user_key`);
@@ -0,0 +1,3 @@
+-- 已清理的软删除记录不会恢复
+DROP INDEX IF EXISTS `idx_smart_elf_signature`;
+DROP INDEX IF EXISTS `idx_smart_elf_project_key`;
@@ -3,7 +3,7 @@
 # Smart Elf 数据库初始化和启动脚本（使用SQLite）
 
 echo "Smart Elf 启动脚本（读取 conf/config.yaml 配置）"
-echo "数据库与飞书配置由 conf/config.yaml 提供，应用启动时自动迁移表结构（GORM AutoMigrate）。"
+echo "数据库与飞书配置由 conf/config.yaml 提供，应用启动时自动应用未执行的数据库迁移（pkg/database/migrations）。"
 
 # 删除旧的数据库文件（可选，仅用于开发环境）
 if [ -f ./smart_elf.db ]; then
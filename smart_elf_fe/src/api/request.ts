@@ -1,11 +1,54 @@
 import axios from 'axios';
 import { apiHost } from '../constants/index';
-import { getLang, getUserKey } from '../utils/index';
+import { getLang } from '../utils/index';
+import sdk from '../utils/sdk';
 
 
 // 创建 axios 实例
 const request = axios;
 
+// 插件会话，后端按会话识别调用方的 user_key，前端不再自行携带
+interface PluginSession {
+  session: string;
+  user_key: string;
+  expire_at: number;
+}
+
+// 会话过期前提前刷新的秒数
+const sessionRefreshAheadSeconds = 60;
+
+let pluginSession: PluginSession | null = null;
+let pluginSessionPromise: Promise<PluginSession> | null = null;
+
+// 使用 JSSDK 授权码换取插件会话，会话有效期内复用
+const getSession = async (): Promise<PluginSession> => {
+  if (
+    pluginSession &&
+    pluginSession.expire_at - sessionRefreshAheadSeconds > Date.now() / 1000
+  ) {
+    return pluginSession;
+  }
+  if (!pluginSessionPromise) {
+    pluginSessionPromise = sdk.utils
+      .getAuthCode()
+      .then(({ code }) =>
+        // 使用独立实例，避免经过本拦截器递归获取会话
+        axios.create().post(`${apiHost}/api/v1/auth/session`, { code })
+      )
+      .then((res) => {
+        if (res.data?.err_code !== 0 || !res.data?.data) {
+          throw new Error(`create session failed: ${res.data?.err_msg}`);
+        }
+        pluginSession = res.data.data as PluginSession;
+        return pluginSession;
+      })
+      .finally(() => {
+        pluginSessionPromise = null;
+      });
+  }
+  return pluginSessionPromise;
+};
+
 // 请求拦截器
 request.interceptors.request.use(
   async (config) => {
@@ -15,7 +58,8 @@ request.interceptors.request.use(
       config.url = apiHost + config.url;
     }
     const lang = await getLang();
-    config.headers['X-USER-KEY'] = await getUserKey();
+    const { session } = await getSession();
+    config.headers.Authorization = `Bearer ${session}`;
     config.headers.locale = lang;
 
     return config;
@@ -42,6 +86,11 @@ request.interceptors.response.use(
   (error) => {
     // 对响应错误做些什么
     // toastCallBack(error);
+    // 会话失效（如服务重启后签名密钥变化）时丢弃缓存的会话，重新获取后重试一次
+    if (error.response?.status === 401 && !error.config?._sessionRetried) {
+      pluginSession = null;
+      return request({ ...error.config, _sessionRetried: true });
+    }
     return Promise.reject(error);
   }
 );
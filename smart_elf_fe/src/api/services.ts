@@ -31,6 +31,11 @@ export interface ISmartElf {
     api_user_key: string;
     work_item_template_id: number;
     work_item_api_name: string;
+    group_name_template?: string;
+    group_role_key?: string;
+    group_oncall_open_ids?: string[];
+    group_invite_mentions?: boolean;
+    alert_chat_id?: string;
   };
 }
 
@@ -71,16 +76,19 @@ export const fetchWorkObjectFields = (
   workItemKey: string
 ) =>
   request
-    .post<unknown, ResponseWrap<WorkObjectField[]>>(
-     `${apiHost}/proxy/open_api/${projectKey}/field/all`,
+    .get<unknown, ResponseWrap<{ fields?: WorkObjectField[] }>>(
+      `${apiHost}/api/v1/metadata`,
       {
-        work_item_type_key: workItemKey,
+        params: {
+          project_key: projectKey,
+          work_item_type_key: workItemKey,
+        },
       }
     )
     .then(({ err_code, data }) => {
       let curData: WorkObjectField[] = [];
-      if (Array.isArray(data)) {
-        curData = data
+      if (Array.isArray(data?.fields)) {
+        curData = data.fields
           .filter((fd) => !filedBlackList.includes(fd.field_key))
           ;
       }
@@ -60,6 +60,7 @@ const getWorkItemTempleteList = async (
 const INIT_VALUES: Partial<ISmartElf["config"]> = {
   reply_switch: false,
   create_group_switch: false,
+  group_invite_mentions: false,
 };
 const config = () => {
   const formApiRef = useRef<any>();
@@ -110,6 +111,11 @@ const config = () => {
           api_user_key,
           work_item_template_id,
           work_item_api_name,
+          group_name_template,
+          group_role_key,
+          group_oncall_open_ids,
+          group_invite_mentions,
+          alert_chat_id,
         } = res?.config;
         const formApi = formApiRef.current;
         formApi.setValues(
@@ -125,6 +131,11 @@ const config = () => {
             work_item_template_id:
               work_item_template_id === 0 ? undefined : work_item_template_id,
             work_item_api_name,
+            group_name_template,
+            group_role_key,
+            group_oncall_open_ids,
+            group_invite_mentions,
+            alert_chat_id,
           },
           { isOverride: true }
         );
@@ -157,6 +168,11 @@ const config = () => {
           api_user_key,
           work_item_template_id,
           work_item_api_name,
+          group_name_template = "",
+          group_role_key = "",
+          group_oncall_open_ids = [],
+          group_invite_mentions = false,
+          alert_chat_id = "",
         } = values;
         updateSmartElfConfig({
           project_key: projectKey,
@@ -173,6 +189,11 @@ const config = () => {
             api_user_key,
             work_item_template_id,
             work_item_api_name,
+            group_name_template,
+            group_role_key,
+            group_oncall_open_ids,
+            group_invite_mentions,
+            alert_chat_id,
           },
         }).then(({ err_code }) => {
           if (err_code === 0) {
@@ -328,6 +349,33 @@ const config = () => {
               onChange={setCheckIsBot}
             />
             </Card>
+            <Card title="群组配置" style={{ marginBottom: 20 }}>
+              <Form.Input
+                field="group_name_template"
+                label="群名称模板"
+                placeholder="默认 [工单]{title}，支持 {title}、{id}、{reporter}"
+              />
+              <Form.Input
+                field="group_role_key"
+                label="入群角色"
+                placeholder="请输入角色key，该角色负责人将被邀请入群"
+              />
+              <Form.TagInput
+                field="group_oncall_open_ids"
+                label="值班人员 Open ID"
+                placeholder="输入后回车添加"
+                style={{ width: "100%" }}
+              />
+              <Form.Switch
+                label="是否邀请消息中@的用户"
+                field="group_invite_mentions"
+              />
+              <Form.Input
+                field="alert_chat_id"
+                label="告警群 Chat ID"
+                placeholder="工单创建失败时通知的群，为空时不通知"
+              />
+            </Card>
           </Skeleton>
         </Card>
     </Form>